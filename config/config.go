@@ -0,0 +1,309 @@
+// Package config loads gocqlsh settings from a cqlshrc compatible file.
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPath returns the location cqlsh reads its config from,
+// ~/.cassandra/cqlshrc.
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".cassandra", "cqlshrc")
+}
+
+// HistoryPath returns the file the history of the interactive shell is saved
+// in, ~/.cassandra/gocqlsh_history.
+func HistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".cassandra", "gocqlsh_history")
+}
+
+type Config struct {
+	Authentication Authentication
+	Connection     Connection
+	SSL            SSL
+	UI             UI
+}
+
+type Authentication struct {
+	Username string
+	Password string
+	Keyspace string
+}
+
+type Connection struct {
+	Hostname       string
+	Port           int
+	Protocol       int
+	Timeout        time.Duration
+	ConnectTimeout time.Duration
+	SSL            bool
+	Consistency    string
+}
+
+type SSL struct {
+	// CertFile is the CA used to verify the server certificate.
+	CertFile string
+	UserCert string
+	UserKey  string
+	// Validate enables hostname validation of the server certificate.
+	Validate bool
+}
+
+type UI struct {
+	Color bool
+	// TimeZone is the location timestamps are displayed in.
+	TimeZone *time.Location
+	// TimeFormat is the Go layout used to display timestamps, in the
+	// file it is given as a strftime format as datetimeformat.
+	TimeFormat      string
+	FloatPrecision  int
+	DoublePrecision int
+	// Format is the name of the format query results are written in, it is
+	// checked when the shell starts.
+	Format string
+}
+
+// Default returns the settings used when neither the config file nor a flag
+// provide a value.
+func Default() *Config {
+	return &Config{
+		Connection: Connection{
+			Port:           9042,
+			Timeout:        10 * time.Second,
+			ConnectTimeout: 5 * time.Second,
+			Consistency:    "ONE",
+		},
+		SSL: SSL{
+			Validate: true,
+		},
+		UI: UI{
+			Color:           true,
+			TimeZone:        time.Local,
+			TimeFormat:      "2006-01-02 15:04:05.000-0700",
+			FloatPrecision:  5,
+			DoublePrecision: 5,
+			Format:          "table",
+		},
+	}
+}
+
+// Load merges the settings in the file at path into c, if the file does
+// not exist and mustExist is false c is left unchanged.
+func (c *Config) Load(path string, mustExist bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) && !mustExist {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	if err := c.Parse(f); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// Parse merges the settings from r into c, unknown sections and options are
+// ignored so that files shared with cqlsh can be used.
+func (c *Config) Parse(r io.Reader) error {
+	var section string
+	s := bufio.NewScanner(r)
+	for lineno := 1; s.Scan(); lineno++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return fmt.Errorf("line %d: invalid section header %q", lineno, line)
+			}
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+
+		i := strings.IndexAny(line, "=:")
+		if i < 0 {
+			return fmt.Errorf("line %d: expected option = value got %q", lineno, line)
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+
+		if err := c.set(section, key, value); err != nil {
+			return fmt.Errorf("line %d: [%s] %s: %v", lineno, section, key, err)
+		}
+	}
+
+	return s.Err()
+}
+
+func (c *Config) set(section, key, value string) error {
+	var err error
+	switch section {
+	case "authentication":
+		switch key {
+		case "username":
+			c.Authentication.Username = value
+		case "password":
+			c.Authentication.Password = value
+		case "keyspace":
+			c.Authentication.Keyspace = value
+		}
+	case "connection":
+		switch key {
+		case "hostname":
+			c.Connection.Hostname = value
+		case "port":
+			c.Connection.Port, err = strconv.Atoi(value)
+		case "protocol_version":
+			c.Connection.Protocol, err = strconv.Atoi(value)
+		case "timeout", "request_timeout":
+			c.Connection.Timeout, err = parseSeconds(value)
+		case "connect_timeout":
+			c.Connection.ConnectTimeout, err = parseSeconds(value)
+		case "ssl":
+			c.Connection.SSL, err = parseBool(value)
+		case "consistency_level":
+			c.Connection.Consistency = value
+		}
+	case "ssl":
+		switch key {
+		case "certfile":
+			c.SSL.CertFile = ExpandHome(value)
+		case "usercert":
+			c.SSL.UserCert = ExpandHome(value)
+		case "userkey":
+			c.SSL.UserKey = ExpandHome(value)
+		case "validate":
+			c.SSL.Validate, err = parseBool(value)
+		}
+	case "ui":
+		switch key {
+		case "color":
+			c.UI.Color, err = parseBool(value)
+		case "timezone":
+			c.UI.TimeZone, err = time.LoadLocation(value)
+		case "datetimeformat":
+			c.UI.TimeFormat, err = strftimeLayout(value)
+		case "float_precision":
+			c.UI.FloatPrecision, err = strconv.Atoi(value)
+		case "double_precision":
+			c.UI.DoublePrecision, err = strconv.Atoi(value)
+		case "format":
+			c.UI.Format = value
+		}
+	}
+
+	return err
+}
+
+func parseBool(v string) (bool, error) {
+	// accept the same values as python's configparser
+	switch strings.ToLower(v) {
+	case "1", "yes", "true", "on":
+		return true, nil
+	case "0", "no", "false", "off":
+		return false, nil
+	}
+
+	return false, fmt.Errorf("invalid boolean %q", v)
+}
+
+func parseSeconds(v string) (time.Duration, error) {
+	secs, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(secs * float64(time.Second)), nil
+}
+
+// ExpandHome replaces a leading ~ in path with the current users home directory.
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, path[1:])
+}
+
+// strftimeLayout converts a python strftime format string into a Go time layout.
+func strftimeLayout(format string) (string, error) {
+	var layout strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			layout.WriteByte(format[i])
+			continue
+		}
+
+		i++
+		if i == len(format) {
+			return "", fmt.Errorf("incomplete directive at end of %q", format)
+		}
+
+		switch format[i] {
+		case 'Y':
+			layout.WriteString("2006")
+		case 'y':
+			layout.WriteString("06")
+		case 'm':
+			layout.WriteString("01")
+		case 'd':
+			layout.WriteString("02")
+		case 'H':
+			layout.WriteString("15")
+		case 'I':
+			layout.WriteString("03")
+		case 'M':
+			layout.WriteString("04")
+		case 'S':
+			layout.WriteString("05")
+		case 'f':
+			layout.WriteString("000000")
+		case 'p':
+			layout.WriteString("PM")
+		case 'z':
+			layout.WriteString("-0700")
+		case 'Z':
+			layout.WriteString("MST")
+		case 'j':
+			layout.WriteString("002")
+		case 'a':
+			layout.WriteString("Mon")
+		case 'A':
+			layout.WriteString("Monday")
+		case 'b':
+			layout.WriteString("Jan")
+		case 'B':
+			layout.WriteString("January")
+		case '%':
+			layout.WriteByte('%')
+		default:
+			return "", fmt.Errorf("unsupported directive %%%c in %q", format[i], format)
+		}
+	}
+
+	return layout.String(), nil
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	const rc = `
+; shared with cqlsh
+[authentication]
+username = cassandra
+password: secret
+keyspace = ks
+
+[connection]
+hostname = 10.0.0.1,10.0.0.2
+port = 9142
+request_timeout = 2.5
+connect_timeout = 3
+ssl = true
+consistency_level = LOCAL_QUORUM
+
+[ssl]
+certfile = /etc/ca.pem
+validate = false
+
+[ui]
+color = off
+timezone = UTC
+datetimeformat = %Y-%m-%d %H:%M:%S%z
+float_precision = 3
+format = json
+
+[copy]
+numprocesses = 4
+`
+
+	cfg := Default()
+	if err := cfg.Parse(strings.NewReader(rc)); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Authentication != (Authentication{"cassandra", "secret", "ks"}) {
+		t.Errorf("unexpected authentication: %+v", cfg.Authentication)
+	}
+
+	conn := Connection{
+		Hostname:       "10.0.0.1,10.0.0.2",
+		Port:           9142,
+		Timeout:        2500 * time.Millisecond,
+		ConnectTimeout: 3 * time.Second,
+		SSL:            true,
+		Consistency:    "LOCAL_QUORUM",
+	}
+	if cfg.Connection != conn {
+		t.Errorf("expected connection %+v got %+v", conn, cfg.Connection)
+	}
+
+	if cfg.SSL != (SSL{CertFile: "/etc/ca.pem"}) {
+		t.Errorf("unexpected ssl: %+v", cfg.SSL)
+	}
+
+	if cfg.UI.Color {
+		t.Error("expected color to be disabled")
+	} else if cfg.UI.TimeZone != time.UTC {
+		t.Errorf("expected UTC time zone got %v", cfg.UI.TimeZone)
+	} else if cfg.UI.TimeFormat != "2006-01-02 15:04:05-0700" {
+		t.Errorf("unexpected time format %q", cfg.UI.TimeFormat)
+	} else if cfg.UI.FloatPrecision != 3 || cfg.UI.DoublePrecision != 5 {
+		t.Errorf("unexpected precision float=%d double=%d", cfg.UI.FloatPrecision, cfg.UI.DoublePrecision)
+	} else if cfg.UI.Format != "json" {
+		t.Errorf("expected json format got %q", cfg.UI.Format)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := [...]string{
+		"[connection\nport = 1",
+		"[connection]\nport",
+		"[connection]\nport = abc",
+		"[connection]\nssl = maybe",
+		"[ui]\ntimezone = Nowhere/Special",
+		"[ui]\ndatetimeformat = %Q",
+	}
+
+	for _, test := range tests {
+		if err := Default().Parse(strings.NewReader(test)); err == nil {
+			t.Errorf("expected error parsing %q", test)
+		}
+	}
+}
+
+func TestStrftimeLayout(t *testing.T) {
+	tests := [...]struct {
+		format, layout string
+	}{
+		{"%Y-%m-%d %H:%M:%S%z", "2006-01-02 15:04:05-0700"},
+		{"%d/%m/%y %I:%M %p", "02/01/06 03:04 PM"},
+		{"%H:%M:%S.%f %Z", "15:04:05.000000 MST"},
+		{"100%%", "100%"},
+	}
+
+	for _, test := range tests {
+		layout, err := strftimeLayout(test.format)
+		if err != nil {
+			t.Errorf("%q: %v", test.format, err)
+		} else if layout != test.layout {
+			t.Errorf("%q: expected %q got %q", test.format, test.layout, layout)
+		}
+	}
+}
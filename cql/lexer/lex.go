@@ -43,6 +43,10 @@ func (i ItemType) String() string {
 		return "SEMICOLON"
 	case ItemDot:
 		return "."
+	case ItemComment:
+		return "COMMENT"
+	case ItemEquals:
+		return "="
 	default:
 		return fmt.Sprintf("UNKOWN_ITEM_%d", i)
 	}
@@ -69,6 +73,10 @@ const (
 	ItemBracket
 	ItemSemiColon
 	ItemDot
+	ItemEquals
+
+	// -- line, // line or /* block */ comments
+	ItemComment
 )
 
 const eof = 0
@@ -232,9 +240,12 @@ func (l *Lexer) nextToken() string {
 	const (
 		START state = iota
 		IN_QUOTE
+		IN_DOLLAR_QUOTE
 		IN_IDENT
 		IN_SPACE
 		IN_NUMBER
+		IN_LINE_COMMENT
+		IN_BLOCK_COMMENT
 	)
 
 	pos := l.start
@@ -254,6 +265,15 @@ loop:
 			if !unicode.IsSpace(r) {
 				break loop
 			}
+		case IN_LINE_COMMENT:
+			if r == '\n' {
+				break loop
+			}
+		case IN_BLOCK_COMMENT:
+			if r == '/' && pos-l.start > 2 && l.in[pos-1] == '*' {
+				pos++
+				break loop
+			}
 		case IN_NUMBER:
 			switch r {
 			case '.', 'e', 'E', '-', '+', 'x', 'X':
@@ -274,26 +294,52 @@ loop:
 				// TODO: is next ' for escape quote?
 			}
 
+		case IN_DOLLAR_QUOTE:
+			if strings.HasPrefix(l.in[pos:], "$$") {
+				pos += 2
+				break loop
+			}
+
 		case IN_IDENT:
 			switch r {
-			case '(', ')', ',', '.':
+			case '(', ')', ',', '.', ';', '=':
 				break loop
 			}
 
-			if unicode.IsSpace(r) {
+			if unicode.IsSpace(r) || acceptPrefix(l.in[pos:], "--", "//", "/*") {
 				break loop
 			}
 
 		case START:
 			switch r {
-			case '(', ')', ',', '.':
+			case '(', ')', ',', '.', ';', '=':
 				pos++
 				break loop
 			case '"', '\'':
 				quote = r
 				st = IN_QUOTE
+			case '$':
+				if strings.HasPrefix(l.in[pos:], "$$") {
+					// skip both dollars so that $$$$ is an empty string
+					pos++
+					st = IN_DOLLAR_QUOTE
+				} else {
+					st = IN_IDENT
+				}
 			case '-':
-				st = IN_NUMBER
+				if strings.HasPrefix(l.in[pos:], "--") {
+					st = IN_LINE_COMMENT
+				} else {
+					st = IN_NUMBER
+				}
+			case '/':
+				if strings.HasPrefix(l.in[pos:], "//") {
+					st = IN_LINE_COMMENT
+				} else if strings.HasPrefix(l.in[pos:], "/*") {
+					st = IN_BLOCK_COMMENT
+				} else {
+					st = IN_IDENT
+				}
 			default:
 				if unicode.IsSpace(r) {
 					st = IN_SPACE
@@ -333,6 +379,10 @@ func (l *Lexer) Item() Item {
 		return Item{ItemSemiColon, token}
 	} else if token == "." {
 		return Item{ItemDot, token}
+	} else if token == "=" {
+		return Item{ItemEquals, token}
+	} else if acceptPrefix(token, "--", "//", "/*") {
+		return Item{ItemComment, token}
 	}
 
 	ch, _ := utf8.DecodeRuneInString(token)
@@ -342,7 +392,7 @@ func (l *Lexer) Item() Item {
 		return Item{ItemWhitespace, token}
 	} else if isDigit(ch) || ch == '-' {
 		return scanNumber(token)
-	} else if ch == '\'' {
+	} else if ch == '\'' || strings.HasPrefix(token, "$$") {
 		return Item{ItemString, token}
 	}
 
@@ -357,6 +407,11 @@ func (l *Lexer) Item() Item {
 	return Item{ItemError, token}
 }
 
+// Pos returns the offset into the input of the end of the last item.
+func (l *Lexer) Pos() int {
+	return l.start
+}
+
 func (l *Lexer) Tokens() []Item {
 	var items []Item
 
@@ -370,10 +425,11 @@ func (l *Lexer) Tokens() []Item {
 	}
 }
 
+// ItemNoWS returns the next item which is not whitespace or a comment.
 func (l *Lexer) ItemNoWS() Item {
 	for {
 		item := l.Item()
-		if item.Typ != ItemWhitespace {
+		if item.Typ != ItemWhitespace && item.Typ != ItemComment {
 			return item
 		}
 	}
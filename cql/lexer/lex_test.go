@@ -42,7 +42,7 @@ func TestLex(t *testing.T) {
 		},
 		{
 			ItemString,
-			[]string{"'raw string'", "'escaped ''string'"},
+			[]string{"'raw string'", "'escaped ''string'", "$$dollar 'quoted' string$$", "$$$$"},
 		},
 		{
 			ItemUUID,
@@ -72,6 +72,14 @@ func TestLex(t *testing.T) {
 			ItemDot,
 			[]string{"."},
 		},
+		{
+			ItemEquals,
+			[]string{"="},
+		},
+		{
+			ItemComment,
+			[]string{"-- comment", "// comment", "/* block */", "/* multi\nline */", "/**/"},
+		},
 	}
 
 	for _, test := range tests {
@@ -101,6 +109,15 @@ func TestLexScanToken(t *testing.T) {
 		{`  "quoted"`, []string{"  ", `"quoted"`, ""}},
 		{"table(column, col2)", []string{"table", "(", "column", ",", " ", "col2", ")", ""}},
 		{"keyspace.table", []string{"keyspace", ".", "table", ""}},
+		{"select 1;", []string{"select", " ", "1", ";", ""}},
+		{"use ks;use", []string{"use", " ", "ks", ";", "use", ""}},
+		{"'a;b';", []string{"'a;b'", ";", ""}},
+		{"AS $$return a;$$;", []string{"AS", " ", "$$return a;$$", ";", ""}},
+		{"$$unterminated;", []string{"$$unterminated;", ""}},
+		{"a -- comment\nb", []string{"a", " ", "-- comment", "\n", "b", ""}},
+		{"a/* c */b", []string{"a", "/* c */", "b", ""}},
+		{"-1", []string{"-1", ""}},
+		{"header=true AND a = 'b'", []string{"header", "=", "true", " ", "AND", " ", "a", " ", "=", " ", "'b'", ""}},
 	}
 
 	for _, test := range tests {
@@ -1,39 +1,267 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/gocql/gocqlsh/config"
+	"github.com/gocql/gocqlsh/metadata"
 	"github.com/gocql/gocqlsh/repl"
 
 	"github.com/chzyer/readline"
 	"github.com/gocql/gocql"
 )
 
-func connect(addr string) (*gocql.Session, error) {
+var defaults = config.Default()
+
+var (
+	flagCqlshrc = flag.String("cqlshrc", "", "config file to read settings from (default ~/.cassandra/cqlshrc)")
+
+	flagPort           = flag.Int("port", defaults.Connection.Port, "native transport port to connect to")
+	flagProtocol       = flag.Int("protocol", defaults.Connection.Protocol, "native protocol version to use, 0 to negotiate with the server")
+	flagConnectTimeout = flag.Duration("connect-timeout", defaults.Connection.ConnectTimeout, "timeout for establishing connections")
+	flagTimeout        = flag.Duration("timeout", defaults.Connection.Timeout, "timeout for each request")
+	flagKeyspace       = flag.String("k", "", "keyspace to authenticate and use")
+	flagConsistency    = flag.String("consistency", defaults.Connection.Consistency, "consistency level for queries")
+
+	flagSSL      = flag.Bool("ssl", false, "connect using TLS, implied by any of the ssl file options")
+	flagSSLCA    = flag.String("ssl-ca", "", "PEM file of CA certificates to verify the server with")
+	flagSSLCert  = flag.String("ssl-cert", "", "PEM client certificate file")
+	flagSSLKey   = flag.String("ssl-key", "", "PEM client private key file")
+	flagNoVerify = flag.Bool("no-verify", false, "do not validate the server hostname against its certificate")
+
+	flagNoColor = flag.Bool("no-color", false, "disable colored output")
+	flagFormat  = flag.String("format", defaults.UI.Format, "format to write results in, one of table, json, ndjson, csv, tsv or markdown")
+
+	flagExecute         = flag.String("e", "", "execute the given statements and exit")
+	flagFile            = flag.String("f", "", "execute the statements in the given file and exit")
+	flagContinueOnError = flag.Bool("continue-on-error", false, "with -e or -f, keep executing statements after one fails")
+	flagDumpSchema      = flag.String("dump-schema", "", "write the CQL to recreate every non-system keyspace to the given file and exit")
+
+	flagUsername string
+	flagPassword string
+)
+
+func init() {
+	flag.StringVar(&flagUsername, "u", "", "username to authenticate with")
+	flag.StringVar(&flagUsername, "username", "", "alias for -u")
+	flag.StringVar(&flagPassword, "p", "", "password to authenticate with, prompted for if a username is given without one")
+	flag.StringVar(&flagPassword, "password", "", "alias for -p")
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [options] [host [host...]]\n\n", os.Args[0])
+	fmt.Fprintln(os.Stderr, "Hosts may also be given as a comma separated list, if none are given the")
+	fmt.Fprintln(os.Stderr, "hostname from the cqlshrc file is used.")
+	fmt.Fprintln(os.Stderr, "\noptions:")
+	flag.PrintDefaults()
+}
+
+// hosts returns the contact points given as arguments, each argument may contain
+// multiple comma separated hosts.
+func hosts(args []string) []string {
+	var hosts []string
+	for _, arg := range args {
+		for _, host := range strings.Split(arg, ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return hosts
+}
+
+// loadConfig reads the cqlshrc file and applies any flags which were set on
+// the command line over the settings from the file.
+func loadConfig() (*config.Config, error) {
+	cfg := config.Default()
+
+	path, mustExist := *flagCqlshrc, true
+	if path == "" {
+		path, mustExist = config.DefaultPath(), false
+	}
+	if path != "" {
+		if err := cfg.Load(path, mustExist); err != nil {
+			return nil, err
+		}
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Connection.Port = *flagPort
+		case "protocol":
+			cfg.Connection.Protocol = *flagProtocol
+		case "connect-timeout":
+			cfg.Connection.ConnectTimeout = *flagConnectTimeout
+		case "timeout":
+			cfg.Connection.Timeout = *flagTimeout
+		case "k":
+			cfg.Authentication.Keyspace = *flagKeyspace
+		case "consistency":
+			cfg.Connection.Consistency = *flagConsistency
+		case "ssl":
+			cfg.Connection.SSL = *flagSSL
+		case "ssl-ca":
+			cfg.Connection.SSL = true
+			cfg.SSL.CertFile = *flagSSLCA
+		case "ssl-cert":
+			cfg.Connection.SSL = true
+			cfg.SSL.UserCert = *flagSSLCert
+		case "ssl-key":
+			cfg.Connection.SSL = true
+			cfg.SSL.UserKey = *flagSSLKey
+		case "no-verify":
+			cfg.SSL.Validate = !*flagNoVerify
+		case "no-color":
+			cfg.UI.Color = !*flagNoColor
+		case "format":
+			cfg.UI.Format = *flagFormat
+		case "u", "username":
+			cfg.Authentication.Username = flagUsername
+		case "p", "password":
+			cfg.Authentication.Password = flagPassword
+		}
+	})
+
+	if args := flag.Args(); len(args) > 0 {
+		cfg.Connection.Hostname = strings.Join(args, ",")
+	}
+
+	return cfg, nil
+}
+
+func newCluster(cfg *config.Config) (*gocql.ClusterConfig, error) {
 	// TODO: use a single conn not a session?
-	cluster := gocql.NewCluster(addr)
-	return cluster.CreateSession()
+	cluster := gocql.NewCluster(hosts([]string{cfg.Connection.Hostname})...)
+	cluster.Port = cfg.Connection.Port
+	cluster.ProtoVersion = cfg.Connection.Protocol
+	cluster.ConnectTimeout = cfg.Connection.ConnectTimeout
+	cluster.Timeout = cfg.Connection.Timeout
+	cluster.Keyspace = cfg.Authentication.Keyspace
+	// see metadata.SchemaEvents for what invalidates the cached schema
+	cluster.FrameHeaderObserver = &metadata.SchemaEvents{}
+
+	consistency, err := gocql.ParseConsistencyWrapper(cfg.Connection.Consistency)
+	if err != nil {
+		return nil, err
+	}
+	cluster.Consistency = consistency
+
+	if auth := cfg.Authentication; auth.Username != "" {
+		cluster.Authenticator = gocql.PasswordAuthenticator{
+			Username: auth.Username,
+			Password: auth.Password,
+		}
+	}
+
+	if cfg.Connection.SSL {
+		ssl := &sslConfig{
+			caFile:   cfg.SSL.CertFile,
+			certFile: cfg.SSL.UserCert,
+			keyFile:  cfg.SSL.UserKey,
+			noVerify: !cfg.SSL.Validate,
+		}
+
+		cluster.SslOpts, err = ssl.sslOptions()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cluster, nil
 }
 
 func main() {
-	db, err := connect(os.Args[1])
+	flag.Usage = usage
+	flag.Parse()
+
+	cfg, err := loadConfig()
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
 
+	if err := repl.CheckFormat(cfg.UI.Format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if len(hosts([]string{cfg.Connection.Hostname})) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	modes := 0
+	for _, mode := range []string{*flagExecute, *flagFile, *flagDumpSchema} {
+		if mode != "" {
+			modes++
+		}
+	}
+
+	switch {
+	case modes > 1:
+		fmt.Fprintln(os.Stderr, "only one of -e, -f and -dump-schema may be given")
+		os.Exit(2)
+	case *flagExecute != "":
+		os.Exit(execute(cfg, func(cql *repl.CQL) error {
+			return cql.Execute(*flagExecute, *flagContinueOnError)
+		}))
+	case *flagFile != "":
+		os.Exit(execute(cfg, func(cql *repl.CQL) error {
+			return cql.ExecuteFile(*flagFile, *flagContinueOnError)
+		}))
+	case *flagDumpSchema != "":
+		os.Exit(execute(cfg, func(cql *repl.CQL) error {
+			return cql.DumpSchema(*flagDumpSchema)
+		}))
+	}
+
+	history := config.HistoryPath()
+	if history != "" {
+		// readline does not create the directory, without it history is
+		// silently not saved
+		if err := os.MkdirAll(filepath.Dir(history), 0700); err != nil {
+			log.Fatalf("unable to create history directory: %v", err)
+		}
+	}
+
+	// statements are added to the history once they are complete by the
+	// shell so that multi-line statements are saved as a single entry
 	r, err := readline.NewEx(&readline.Config{
-		Prompt: "gocqlsh> ",
+		HistoryFile:            history,
+		DisableAutoSaveHistory: true,
+		HistorySearchFold:      true,
 	})
-
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer r.Close()
 
-	cql := repl.New(db, r)
+	if auth := &cfg.Authentication; auth.Username != "" && auth.Password == "" {
+		password, err := r.ReadPassword("Password: ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		auth.Password = string(password)
+	}
+
+	cluster, err := newCluster(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := cluster.CreateSession()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	cql := repl.New(cfg, cluster, db, r)
 	if err := cql.Run(); err != nil {
 		if err == io.EOF {
 			return
@@ -42,3 +270,36 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// execute runs statements with run without an interactive shell and returns
+// the exit code for the process.
+func execute(cfg *config.Config, run func(*repl.CQL) error) int {
+	if auth := cfg.Authentication; auth.Username != "" && auth.Password == "" {
+		fmt.Fprintln(os.Stderr, "a password is required when executing statements with a username")
+		return 2
+	}
+
+	if !readline.IsTerminal(int(os.Stdout.Fd())) {
+		cfg.UI.Color = false
+	}
+
+	cluster, err := newCluster(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	db, err := cluster.CreateSession()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer db.Close()
+
+	cql := repl.NewExec(cfg, cluster, db, os.Stdout, os.Stderr)
+	if err := run(cql); err != nil {
+		return 1
+	}
+
+	return 0
+}
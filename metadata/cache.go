@@ -0,0 +1,127 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"github.com/gocql/gocql"
+)
+
+// SchemaEvents is a gocql.FrameHeaderObserver which records that the
+// cluster has sent an event. The control connection is sent an event when
+// the schema changes, as the observer only sees the frame header the keyspace
+// which changed is unknown and any event, including topology and status
+// changes, is treated as a change to every keyspace. These are rare enough
+// that reading the schema again is cheaper than showing a stale one.
+type SchemaEvents struct {
+	received int32
+}
+
+func (e *SchemaEvents) ObserveFrameHeader(_ context.Context, h gocql.ObservedFrameHeader) {
+	// events are the only frames sent on stream -1
+	if h.Stream == -1 {
+		atomic.StoreInt32(&e.received, 1)
+	}
+}
+
+// take reports whether an event has been received since it was last called.
+func (e *SchemaEvents) take() bool {
+	return atomic.SwapInt32(&e.received, 0) == 1
+}
+
+// Watch invalidates the cached schema whenever events receives an event.
+func (c *Cassandra) Watch(events *SchemaEvents) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = events
+}
+
+// Invalidate drops the cached schema of keyspace, or of every keyspace if it
+// is empty, so that it is read from the cluster when it is next used.
+func (c *Cassandra) Invalidate(keyspace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidate(keyspace)
+}
+
+func (c *Cassandra) invalidate(keyspace string) {
+	if keyspace == "" {
+		c.keyspaces = nil
+	} else {
+		delete(c.keyspaces, keyspace)
+	}
+	c.all = false
+}
+
+// checkEvents invalidates the whole cache if the cluster has sent an event.
+func (c *Cassandra) checkEvents() {
+	if c.events != nil && c.events.take() {
+		c.invalidate("")
+	}
+}
+
+// Keyspace returns the schema of the keyspace called name, it is read from
+// the cluster if it is not cached. The schema is shared and must not be
+// modified.
+func (c *Cassandra) Keyspace(name string) (*Keyspace, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checkEvents()
+	if ks, ok := c.keyspaces[name]; ok {
+		return ks, nil
+	}
+
+	r, err := c.reader()
+	if err != nil {
+		return nil, err
+	}
+
+	keyspaces, err := r.read(name)
+	if err != nil {
+		return nil, err
+	} else if len(keyspaces) == 0 {
+		return nil, fmt.Errorf("keyspace %s does not exist", QuoteIdentifier(name))
+	}
+
+	if c.keyspaces == nil {
+		c.keyspaces = make(map[string]*Keyspace)
+	}
+	c.keyspaces[name] = keyspaces[0]
+	return keyspaces[0], nil
+}
+
+// Keyspaces returns the schema of every keyspace ordered by name, they are
+// read from the cluster unless every keyspace is cached.
+func (c *Cassandra) Keyspaces() ([]*Keyspace, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checkEvents()
+	if !c.all {
+		r, err := c.reader()
+		if err != nil {
+			return nil, err
+		}
+
+		keyspaces, err := r.read("")
+		if err != nil {
+			return nil, err
+		}
+
+		c.keyspaces = make(map[string]*Keyspace, len(keyspaces))
+		for _, ks := range keyspaces {
+			c.keyspaces[ks.Name] = ks
+		}
+		c.all = true
+	}
+
+	keyspaces := make([]*Keyspace, 0, len(c.keyspaces))
+	for _, ks := range c.keyspaces {
+		keyspaces = append(keyspaces, ks)
+	}
+	sort.Slice(keyspaces, func(i, j int) bool { return keyspaces[i].Name < keyspaces[j].Name })
+	return keyspaces, nil
+}
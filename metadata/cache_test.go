@@ -0,0 +1,85 @@
+package metadata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gocql/gocql"
+)
+
+// countingReader is a schemaReader which counts the keyspaces read.
+type countingReader struct {
+	names []string
+	reads map[string]int
+}
+
+func (r *countingReader) read(keyspace string) ([]*Keyspace, error) {
+	r.reads[keyspace]++
+
+	var keyspaces []*Keyspace
+	for _, name := range r.names {
+		if keyspace == "" || keyspace == name {
+			keyspaces = append(keyspaces, &Keyspace{Name: name})
+		}
+	}
+	return keyspaces, nil
+}
+
+func TestSchemaCache(t *testing.T) {
+	r := &countingReader{names: []string{"shop", "events"}, reads: make(map[string]int)}
+	events := &SchemaEvents{}
+	c := &Cassandra{schemaReader: r}
+	c.Watch(events)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Keyspace("shop"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r.reads["shop"] != 1 {
+		t.Errorf("expected keyspace to be read once got %d", r.reads["shop"])
+	}
+
+	if _, err := c.Keyspace("missing"); err == nil {
+		t.Error("expected error for missing keyspace")
+	}
+
+	keyspaces, err := c.Keyspaces()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(keyspaces) != 2 || keyspaces[0].Name != "events" || keyspaces[1].Name != "shop" {
+		t.Errorf("expected events and shop got %v", keyspaces)
+	}
+
+	// every keyspace is cached once they have all been read
+	c.Keyspaces()
+	c.Keyspace("events")
+	if r.reads[""] != 1 || r.reads["events"] != 0 {
+		t.Errorf("expected only one read of every keyspace got %v", r.reads)
+	}
+
+	c.Invalidate("shop")
+	c.Keyspace("events")
+	c.Keyspace("shop")
+	if r.reads["shop"] != 2 || r.reads["events"] != 0 {
+		t.Errorf("expected only shop to be read again got %v", r.reads)
+	}
+	c.Keyspaces()
+	if r.reads[""] != 2 {
+		t.Errorf("expected every keyspace to be read again got %v", r.reads)
+	}
+
+	// frames on other streams are responses not events
+	events.ObserveFrameHeader(context.Background(), gocql.ObservedFrameHeader{Stream: 3})
+	c.Keyspaces()
+	if r.reads[""] != 2 {
+		t.Errorf("expected a response not to invalidate the schema got %v", r.reads)
+	}
+
+	events.ObserveFrameHeader(context.Background(), gocql.ObservedFrameHeader{Stream: -1})
+	c.Keyspace("events")
+	c.Keyspace("events")
+	if r.reads["events"] != 1 {
+		t.Errorf("expected an event to invalidate the schema once got %v", r.reads)
+	}
+}
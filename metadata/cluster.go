@@ -5,6 +5,7 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/gocql/gocql"
 )
@@ -18,6 +19,16 @@ func New(db *gocql.Session) *Cassandra {
 
 type Cassandra struct {
 	db *gocql.Session
+
+	// mu guards the schema reader and the cached schema
+	mu sync.Mutex
+	// schemaReader is chosen by the version of the cluster once it is known
+	schemaReader schemaReader
+	// keyspaces is the cached schema of each keyspace which has been read,
+	// all is set if it holds every keyspace
+	keyspaces map[string]*Keyspace
+	all       bool
+	events    *SchemaEvents
 }
 
 type Cluster struct {
@@ -26,13 +37,15 @@ type Cluster struct {
 	Name       string
 	Protocol   string
 	Address    net.IP
+	// Partitioner is the class name of the partitioner
+	Partitioner string
 }
 
 func (c *Cassandra) ClusterMeta() (*Cluster, error) {
 	meta := &Cluster{}
 	// TODO: the driver needs a way to export this on a per cluster basis, cluster metadata?
-	err := c.db.Query("SELECT release_version, cql_version, cluster_name, native_protocol_version, listen_address FROM system.local").Scan(
-		&meta.Version, &meta.CQLVersion, &meta.Name, &meta.Protocol, &meta.Address)
+	err := c.db.Query("SELECT release_version, cql_version, cluster_name, native_protocol_version, listen_address, partitioner FROM system.local").Scan(
+		&meta.Version, &meta.CQLVersion, &meta.Name, &meta.Protocol, &meta.Address, &meta.Partitioner)
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +99,11 @@ func (c *Version) unmarshal(data []byte) error {
 	return nil
 }
 
+// AtLeast reports whether c is major.minor or later.
+func (c Version) AtLeast(major, minor int) bool {
+	return c.Major > major || c.Major == major && c.Minor >= minor
+}
+
 func (c Version) String() string {
 	return fmt.Sprintf("v%d.%d.%d", c.Major, c.Minor, c.Patch)
 }
@@ -0,0 +1,397 @@
+package metadata
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// reservedKeywords can only be used as identifiers when they are quoted.
+var reservedKeywords = map[string]bool{
+	"add": true, "allow": true, "alter": true, "and": true, "apply": true, "asc": true, "authorize": true,
+	"batch": true, "begin": true, "by": true, "columnfamily": true, "create": true, "default": true,
+	"delete": true, "desc": true, "describe": true, "drop": true, "entries": true, "execute": true,
+	"from": true, "full": true, "grant": true, "if": true, "in": true, "index": true, "infinity": true,
+	"insert": true, "into": true, "is": true, "keyspace": true, "limit": true, "materialized": true,
+	"mbean": true, "mbeans": true, "modify": true, "nan": true, "norecursive": true, "not": true,
+	"null": true, "of": true, "on": true, "or": true, "order": true, "primary": true, "rename": true,
+	"replace": true, "revoke": true, "schema": true, "select": true, "set": true, "table": true, "to": true,
+	"token": true, "truncate": true, "unlogged": true, "unset": true, "update": true, "use": true,
+	"using": true, "view": true, "where": true, "with": true,
+}
+
+// QuoteIdentifier quotes name if it would not otherwise be read back as the
+// same identifier, ie it contains upper case or non alphanumeric characters
+// or is a reserved keyword.
+func QuoteIdentifier(name string) string {
+	if reservedKeywords[name] {
+		return `"` + name + `"`
+	}
+
+	for i, r := range name {
+		if !(r >= 'a' && r <= 'z') && !(i > 0 && (r >= '0' && r <= '9' || r == '_')) {
+			return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+		}
+	}
+
+	return name
+}
+
+func qualified(keyspace, name string) string {
+	return QuoteIdentifier(keyspace) + "." + QuoteIdentifier(name)
+}
+
+func quoteString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// literal formats an option value as a CQL literal.
+func literal(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return quoteString(v)
+	case []byte:
+		return "0x" + hex.EncodeToString(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case map[string]string:
+		entries := make([]string, 0, len(v))
+		for _, k := range sortedKeys(v) {
+			entries = append(entries, quoteString(k)+": "+quoteString(v[k]))
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	case map[string][]byte:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		entries := make([]string, len(keys))
+		for i, k := range keys {
+			entries[i] = quoteString(k) + ": " + literal(v[k])
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	case []string:
+		quoted := make([]string, len(v))
+		for i, s := range v {
+			quoted[i] = quoteString(s)
+		}
+		return "{" + strings.Join(quoted, ", ") + "}"
+	}
+
+	return fmt.Sprint(v)
+}
+
+// sortedKeys returns the keys of m in order, with class first as it is in
+// replication and compaction options.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i] == "class" || keys[j] == "class" {
+			return keys[i] == "class"
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// clauses returns the options as name = value in order of name, empty
+// collections are omitted.
+func (o Options) clauses() []string {
+	var clauses []string
+	for name, v := range o {
+		if rv := reflect.ValueOf(v); (rv.Kind() == reflect.Map || rv.Kind() == reflect.Slice) && rv.Len() == 0 {
+			continue
+		}
+		clauses = append(clauses, name+" = "+literal(v))
+	}
+
+	sort.Strings(clauses)
+	return clauses
+}
+
+func columnNames(cols []*Column) string {
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = QuoteIdentifier(col.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// primaryKey returns the PRIMARY KEY definition of t, the partition key is
+// bracketed if it is composite.
+func (t *Table) primaryKey() string {
+	key := columnNames(t.PartitionKey)
+	if len(t.PartitionKey) > 1 {
+		key = "(" + key + ")"
+	}
+
+	if len(t.ClusteringColumns) > 0 {
+		key += ", " + columnNames(t.ClusteringColumns)
+	}
+	return "PRIMARY KEY (" + key + ")"
+}
+
+// with returns the WITH clause of t preceded by indent, including the closing
+// semicolon.
+func (t *Table) with(indent string) string {
+	var clauses []string
+	if t.CompactStorage {
+		clauses = append(clauses, "COMPACT STORAGE")
+	}
+
+	if len(t.ClusteringColumns) > 0 {
+		order := make([]string, len(t.ClusteringColumns))
+		for i, col := range t.ClusteringColumns {
+			order[i] = QuoteIdentifier(col.Name) + " ASC"
+			if col.Desc {
+				order[i] = QuoteIdentifier(col.Name) + " DESC"
+			}
+		}
+		clauses = append(clauses, "CLUSTERING ORDER BY ("+strings.Join(order, ", ")+")")
+	}
+
+	clauses = append(clauses, t.Options.clauses()...)
+	if len(clauses) == 0 {
+		return ";"
+	}
+	return indent + "WITH " + strings.Join(clauses, "\n    AND ") + ";"
+}
+
+// CQL returns the CREATE KEYSPACE statement for k.
+func (k *Keyspace) CQL() string {
+	replication := make([]string, 0, len(k.Replication))
+	for _, key := range sortedKeys(k.Replication) {
+		replication = append(replication, quoteString(key)+": "+quoteString(k.Replication[key]))
+	}
+
+	return fmt.Sprintf("CREATE KEYSPACE %s WITH replication = {%s} AND durable_writes = %t;",
+		QuoteIdentifier(k.Name), strings.Join(replication, ", "), k.DurableWrites)
+}
+
+// DDL returns the statements which create k and everything in it, separated
+// by blank lines. Statements are ordered so that they can be executed in
+// turn, types come before the types and tables which use them and each
+// table comes before its indexes and views.
+func (k *Keyspace) DDL() string {
+	stmts := []string{k.CQL()}
+	for _, t := range k.typesInOrder() {
+		stmts = append(stmts, t.CQL())
+	}
+	for _, t := range k.Tables {
+		stmts = append(stmts, t.DDL())
+	}
+	for _, fn := range k.Functions {
+		stmts = append(stmts, fn.CQL())
+	}
+	for _, agg := range k.Aggregates {
+		stmts = append(stmts, agg.CQL())
+	}
+
+	return strings.Join(stmts, "\n\n")
+}
+
+// SchemaDDL returns the statements which create every keyspace in keyspaces
+// other than the system keyspaces.
+func SchemaDDL(keyspaces []*Keyspace) string {
+	var stmts []string
+	for _, ks := range keyspaces {
+		if !ks.System() {
+			stmts = append(stmts, ks.DDL())
+		}
+	}
+
+	return strings.Join(stmts, "\n\n")
+}
+
+// typeName matches the identifiers in a CQL type.
+var typeName = regexp.MustCompile(`"(?:[^"]|"")*"|[A-Za-z][A-Za-z0-9_]*`)
+
+// typesInOrder returns the types of k ordered so that each type comes after
+// the types used by its fields.
+func (k *Keyspace) typesInOrder() []*Type {
+	ordered := make([]*Type, 0, len(k.Types))
+	visited := make(map[*Type]bool)
+
+	var visit func(t *Type)
+	visit = func(t *Type) {
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+
+		for _, field := range t.FieldTypes {
+			for _, name := range typeName.FindAllString(field, -1) {
+				if strings.HasPrefix(name, `"`) {
+					name = strings.Replace(name[1:len(name)-1], `""`, `"`, -1)
+				}
+				if dep := k.Type(name); dep != nil {
+					visit(dep)
+				}
+			}
+		}
+
+		ordered = append(ordered, t)
+	}
+
+	for _, t := range k.Types {
+		visit(t)
+	}
+	return ordered
+}
+
+// CQL returns the CREATE TABLE statement for t.
+func (t *Table) CQL() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n", qualified(t.Keyspace, t.Name))
+
+	inlineKey := len(t.PartitionKey) == 1 && len(t.ClusteringColumns) == 0
+	for i, col := range t.Columns {
+		fmt.Fprintf(&b, "    %s %s", QuoteIdentifier(col.Name), col.Type)
+		switch {
+		case col.Kind == Static:
+			b.WriteString(" static")
+		case inlineKey && col.Kind == PartitionKey:
+			b.WriteString(" PRIMARY KEY")
+		}
+
+		if i < len(t.Columns)-1 || !inlineKey {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+
+	if !inlineKey {
+		fmt.Fprintf(&b, "    %s\n", t.primaryKey())
+	}
+
+	b.WriteString(")" + t.with(" "))
+	return b.String()
+}
+
+// DDL returns the statements which create t, its indexes and its
+// materialized views.
+func (t *Table) DDL() string {
+	stmts := []string{t.CQL()}
+	for _, idx := range t.Indexes {
+		stmts = append(stmts, idx.CQL())
+	}
+	for _, v := range t.Views {
+		stmts = append(stmts, v.CQL())
+	}
+
+	return strings.Join(stmts, "\n\n")
+}
+
+// CQL returns the CREATE MATERIALIZED VIEW statement for v.
+func (v *View) CQL() string {
+	columns := "*"
+	if !v.IncludeAllColumns {
+		columns = columnNames(v.Columns)
+	}
+
+	return fmt.Sprintf("CREATE MATERIALIZED VIEW %s AS\n    SELECT %s\n    FROM %s\n    WHERE %s\n    %s%s",
+		qualified(v.Keyspace, v.Name), columns, qualified(v.Keyspace, v.BaseTable), v.WhereClause, v.primaryKey(),
+		v.with("\n    "))
+}
+
+// indexTarget matches targets such as keys(m) and full(l).
+var indexTarget = regexp.MustCompile(`^(keys|values|entries|full)\((.+)\)$`)
+
+// target returns the column indexed by idx, quoted if necessary.
+func (idx *Index) target() string {
+	target := idx.Options["target"]
+	fn := ""
+	if m := indexTarget.FindStringSubmatch(target); m != nil {
+		fn, target = m[1], m[2]
+	}
+
+	if !strings.HasPrefix(target, `"`) {
+		target = QuoteIdentifier(target)
+	}
+
+	if fn != "" {
+		return fn + "(" + target + ")"
+	}
+	return target
+}
+
+// CQL returns the CREATE INDEX statement for idx.
+func (idx *Index) CQL() string {
+	if idx.Kind != "CUSTOM" {
+		return fmt.Sprintf("CREATE INDEX %s ON %s (%s);", QuoteIdentifier(idx.Name),
+			qualified(idx.Keyspace, idx.Table), idx.target())
+	}
+
+	stmt := fmt.Sprintf("CREATE CUSTOM INDEX %s ON %s (%s) USING %s", QuoteIdentifier(idx.Name),
+		qualified(idx.Keyspace, idx.Table), idx.target(), quoteString(idx.Options["class_name"]))
+
+	opts := make(map[string]string)
+	for k, v := range idx.Options {
+		if k != "target" && k != "class_name" {
+			opts[k] = v
+		}
+	}
+	if len(opts) > 0 {
+		stmt += " WITH OPTIONS = " + literal(opts)
+	}
+	return stmt + ";"
+}
+
+// CQL returns the CREATE TYPE statement for t.
+func (t *Type) CQL() string {
+	fields := make([]string, len(t.FieldNames))
+	for i, name := range t.FieldNames {
+		fields[i] = "    " + QuoteIdentifier(name) + " " + t.FieldTypes[i]
+	}
+
+	return fmt.Sprintf("CREATE TYPE %s (\n%s\n);", qualified(t.Keyspace, t.Name), strings.Join(fields, ",\n"))
+}
+
+// CQL returns the CREATE FUNCTION statement for fn.
+func (fn *Function) CQL() string {
+	args := make([]string, len(fn.ArgumentNames))
+	for i, name := range fn.ArgumentNames {
+		args[i] = QuoteIdentifier(name) + " " + fn.ArgumentTypes[i]
+	}
+
+	onNull := "RETURNS NULL ON NULL INPUT"
+	if fn.CalledOnNullInput {
+		onNull = "CALLED ON NULL INPUT"
+	}
+
+	// the body can only be dollar quoted if it does not contain $$
+	body := "$$" + fn.Body + "$$"
+	if strings.Contains(fn.Body, "$$") {
+		body = quoteString(fn.Body)
+	}
+
+	return fmt.Sprintf("CREATE FUNCTION %s(%s)\n    %s\n    RETURNS %s\n    LANGUAGE %s\n    AS %s;",
+		qualified(fn.Keyspace, fn.Name), strings.Join(args, ", "), onNull, fn.ReturnType, fn.Language, body)
+}
+
+// CQL returns the CREATE AGGREGATE statement for agg.
+func (agg *Aggregate) CQL() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE AGGREGATE %s(%s)\n    SFUNC %s\n    STYPE %s", qualified(agg.Keyspace, agg.Name),
+		strings.Join(agg.ArgumentTypes, ", "), QuoteIdentifier(agg.StateFunc), agg.StateType)
+	if agg.FinalFunc != "" {
+		fmt.Fprintf(&b, "\n    FINALFUNC %s", QuoteIdentifier(agg.FinalFunc))
+	}
+	if agg.InitCond != "" {
+		fmt.Fprintf(&b, "\n    INITCOND %s", agg.InitCond)
+	}
+
+	b.WriteString(";")
+	return b.String()
+}
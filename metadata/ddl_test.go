@@ -0,0 +1,375 @@
+package metadata
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testRows are schema rows as read from the system_schema tables of
+// Cassandra 3.11.
+func testRows() *schemaRows {
+	tableOptions := func(r map[string]interface{}) map[string]interface{} {
+		defaults := map[string]interface{}{
+			"bloom_filter_fp_chance": 0.01,
+			"caching":                map[string]string{"keys": "ALL", "rows_per_partition": "NONE"},
+			"comment":                "",
+			"compaction": map[string]string{
+				"class":         "org.apache.cassandra.db.compaction.SizeTieredCompactionStrategy",
+				"max_threshold": "32",
+				"min_threshold": "4",
+			},
+			"default_time_to_live": 0,
+			"extensions":           map[string][]byte{},
+			"gc_grace_seconds":     864000,
+			"speculative_retry":    "99PERCENTILE",
+		}
+		for name, v := range defaults {
+			if _, ok := r[name]; !ok {
+				r[name] = v
+			}
+		}
+		return r
+	}
+	column := func(table, name, kind, typ string, position int, order string) map[string]interface{} {
+		return map[string]interface{}{
+			"keyspace_name":    "shop",
+			"table_name":       table,
+			"column_name":      name,
+			"kind":             kind,
+			"type":             typ,
+			"position":         position,
+			"clustering_order": order,
+		}
+	}
+
+	return &schemaRows{
+		keyspaces: []map[string]interface{}{
+			{
+				"keyspace_name":  "shop",
+				"durable_writes": true,
+				"replication": map[string]string{
+					"class":   "org.apache.cassandra.locator.NetworkTopologyStrategy",
+					"dc2":     "1",
+					"dc1":     "3",
+					"ignored": "x",
+				},
+			},
+		},
+		tables: []map[string]interface{}{
+			tableOptions(map[string]interface{}{
+				"keyspace_name": "shop",
+				"table_name":    "orders",
+				"flags":         []string{"compound"},
+				"id":            "ignored",
+				"comment":       "it's",
+			}),
+			tableOptions(map[string]interface{}{
+				"keyspace_name": "shop",
+				"table_name":    "Customers",
+				"flags":         []string{"compound"},
+			}),
+			tableOptions(map[string]interface{}{
+				"keyspace_name": "shop",
+				"table_name":    "settings",
+				"flags":         []string{},
+			}),
+		},
+		columns: []map[string]interface{}{
+			column("orders", "placed", "clustering", "timestamp", 1, "desc"),
+			column("orders", "region", "partition_key", "text", 1, "none"),
+			column("orders", "total", "regular", "decimal", -1, "none"),
+			column("orders", "customer", "partition_key", "uuid", 0, "none"),
+			column("orders", "id", "clustering", "timeuuid", 0, "asc"),
+			column("orders", "address", "static", "frozen<address>", -1, "none"),
+			column("orders", "items", "regular", "map<text, int>", -1, "none"),
+			column("Customers", "id", "partition_key", "uuid", 0, "none"),
+			column("Customers", "Name", "regular", "text", -1, "none"),
+			column("Customers", "from", "regular", "text", -1, "none"),
+			// a compact storage table without clustering columns has its
+			// columns stored as static with a hidden clustering and value
+			// column
+			column("settings", "name", "partition_key", "text", 0, "none"),
+			column("settings", "enabled", "static", "boolean", -1, "none"),
+			column("settings", "column1", "clustering", "text", 0, "asc"),
+			column("settings", "value", "regular", "blob", -1, "none"),
+			column("orders_by_total", "total", "partition_key", "decimal", 0, "none"),
+			column("orders_by_total", "customer", "clustering", "uuid", 0, "asc"),
+			column("orders_by_total", "region", "clustering", "text", 1, "asc"),
+			column("orders_by_total", "placed", "clustering", "timestamp", 2, "desc"),
+			column("orders_by_total", "id", "clustering", "timeuuid", 3, "asc"),
+		},
+		views: []map[string]interface{}{
+			{
+				"keyspace_name":       "shop",
+				"view_name":           "orders_by_total",
+				"base_table_name":     "orders",
+				"include_all_columns": false,
+				"where_clause":        "total IS NOT NULL AND customer IS NOT NULL",
+				"gc_grace_seconds":    864000,
+			},
+		},
+		indexes: []map[string]interface{}{
+			{
+				"keyspace_name": "shop",
+				"table_name":    "orders",
+				"index_name":    "orders_items",
+				"kind":          "COMPOSITES",
+				"options":       map[string]string{"target": "keys(items)"},
+			},
+			{
+				"keyspace_name": "shop",
+				"table_name":    "Customers",
+				"index_name":    "customers_name",
+				"kind":          "CUSTOM",
+				"options": map[string]string{
+					"target":     "Name",
+					"class_name": "org.apache.cassandra.index.sasi.SASIIndex",
+					"mode":       "CONTAINS",
+				},
+			},
+		},
+		types: []map[string]interface{}{
+			{
+				"keyspace_name": "shop",
+				"type_name":     "address",
+				"field_names":   []string{"street", "postcode"},
+				"field_types":   []string{"text", "text"},
+			},
+		},
+		functions: []map[string]interface{}{
+			{
+				"keyspace_name":        "shop",
+				"function_name":        "plus",
+				"argument_names":       []string{"total", "amount"},
+				"argument_types":       []string{"decimal", "decimal"},
+				"return_type":          "decimal",
+				"language":             "java",
+				"body":                 "return total.add(amount);",
+				"called_on_null_input": false,
+			},
+		},
+		aggregates: []map[string]interface{}{
+			{
+				"keyspace_name":  "shop",
+				"aggregate_name": "sum_total",
+				"argument_types": []string{"decimal"},
+				"state_func":     "plus",
+				"state_type":     "decimal",
+				"return_type":    "decimal",
+				"initcond":       "0",
+			},
+		},
+	}
+}
+
+func testKeyspace(t *testing.T) *Keyspace {
+	keyspaces := parseSchema(testRows())
+	if len(keyspaces) != 1 {
+		t.Fatalf("expected 1 keyspace got %d", len(keyspaces))
+	}
+	return keyspaces[0]
+}
+
+func TestParseSchema(t *testing.T) {
+	ks := testKeyspace(t)
+
+	orders := ks.Table("orders")
+	if orders == nil {
+		t.Fatal("orders table not found")
+	}
+
+	var names []string
+	for _, col := range orders.Columns {
+		names = append(names, col.Name)
+	}
+	if exp := "[customer region id placed address items total]"; fmt.Sprint(names) != exp {
+		t.Errorf("expected columns %s got %v", exp, names)
+	}
+
+	if len(orders.Views) != 1 || orders.Views[0] != ks.View("orders_by_total") {
+		t.Errorf("expected orders_by_total to be a view of orders got %v", orders.Views)
+	}
+	if ks.Index("customers_name") == nil || ks.Index("orders_by_total") != nil {
+		t.Error("expected to find customers_name index only")
+	}
+	if _, ok := orders.Options["id"]; ok {
+		t.Error("expected id not to be a table option")
+	}
+}
+
+func TestDDL(t *testing.T) {
+	ks := testKeyspace(t)
+
+	tests := [...]struct {
+		name string
+		ddl  string
+		exp  string
+	}{
+		{"keyspace", ks.CQL(), "CREATE KEYSPACE shop WITH replication = {'class': 'org.apache.cassandra.locator.NetworkTopologyStrategy', 'dc1': '3', 'dc2': '1', 'ignored': 'x'} AND durable_writes = true;"},
+		{"table", ks.Table("orders").DDL(), `CREATE TABLE shop.orders (
+    customer uuid,
+    region text,
+    id timeuuid,
+    placed timestamp,
+    address frozen<address> static,
+    items map<text, int>,
+    total decimal,
+    PRIMARY KEY ((customer, region), id, placed)
+) WITH CLUSTERING ORDER BY (id ASC, placed DESC)
+    AND bloom_filter_fp_chance = 0.01
+    AND caching = {'keys': 'ALL', 'rows_per_partition': 'NONE'}
+    AND comment = 'it''s'
+    AND compaction = {'class': 'org.apache.cassandra.db.compaction.SizeTieredCompactionStrategy', 'max_threshold': '32', 'min_threshold': '4'}
+    AND default_time_to_live = 0
+    AND gc_grace_seconds = 864000
+    AND speculative_retry = '99PERCENTILE';
+
+CREATE INDEX orders_items ON shop.orders (keys(items));
+
+CREATE MATERIALIZED VIEW shop.orders_by_total AS
+    SELECT total, customer, region, placed, id
+    FROM shop.orders
+    WHERE total IS NOT NULL AND customer IS NOT NULL
+    PRIMARY KEY (total, customer, region, placed, id)
+    WITH CLUSTERING ORDER BY (customer ASC, region ASC, placed DESC, id ASC)
+    AND gc_grace_seconds = 864000;`},
+		{"inline key", ks.Table("Customers").CQL(), `CREATE TABLE shop."Customers" (
+    id uuid PRIMARY KEY,
+    "Name" text,
+    "from" text
+) WITH bloom_filter_fp_chance = 0.01
+    AND caching = {'keys': 'ALL', 'rows_per_partition': 'NONE'}
+    AND comment = ''
+    AND compaction = {'class': 'org.apache.cassandra.db.compaction.SizeTieredCompactionStrategy', 'max_threshold': '32', 'min_threshold': '4'}
+    AND default_time_to_live = 0
+    AND gc_grace_seconds = 864000
+    AND speculative_retry = '99PERCENTILE';`},
+		{"static compact", ks.Table("settings").CQL(), `CREATE TABLE shop.settings (
+    name text PRIMARY KEY,
+    enabled boolean
+) WITH COMPACT STORAGE
+    AND bloom_filter_fp_chance = 0.01
+    AND caching = {'keys': 'ALL', 'rows_per_partition': 'NONE'}
+    AND comment = ''
+    AND compaction = {'class': 'org.apache.cassandra.db.compaction.SizeTieredCompactionStrategy', 'max_threshold': '32', 'min_threshold': '4'}
+    AND default_time_to_live = 0
+    AND gc_grace_seconds = 864000
+    AND speculative_retry = '99PERCENTILE';`},
+		{"custom index", ks.Index("customers_name").CQL(), `CREATE CUSTOM INDEX customers_name ON shop."Customers" ("Name") USING 'org.apache.cassandra.index.sasi.SASIIndex' WITH OPTIONS = {'mode': 'CONTAINS'};`},
+		{"type", ks.Type("address").CQL(), `CREATE TYPE shop.address (
+    street text,
+    postcode text
+);`},
+		{"function", ks.FunctionsNamed("plus")[0].CQL(), `CREATE FUNCTION shop.plus(total decimal, amount decimal)
+    RETURNS NULL ON NULL INPUT
+    RETURNS decimal
+    LANGUAGE java
+    AS $$return total.add(amount);$$;`},
+		{"aggregate", ks.AggregatesNamed("sum_total")[0].CQL(), `CREATE AGGREGATE shop.sum_total(decimal)
+    SFUNC plus
+    STYPE decimal
+    INITCOND 0;`},
+	}
+
+	for _, test := range tests {
+		if test.ddl != test.exp {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", test.name, test.exp, test.ddl)
+		}
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := [...]struct {
+		name string
+		exp  string
+	}{
+		{"orders", "orders"},
+		{"order_2", "order_2"},
+		{"Orders", `"Orders"`},
+		{"2orders", `"2orders"`},
+		{`say "hi"`, `"say ""hi"""`},
+		// reserved keywords must be quoted to be read back as identifiers
+		{"order", `"order"`},
+		{"limit", `"limit"`},
+		{"token", `"token"`},
+		// unreserved keywords need not be
+		{"key", "key"},
+		{"ttl", "ttl"},
+	}
+
+	for _, test := range tests {
+		if got := QuoteIdentifier(test.name); got != test.exp {
+			t.Errorf("%s: expected %s got %s", test.name, test.exp, got)
+		}
+	}
+}
+
+func TestCompactStorage(t *testing.T) {
+	tests := [...]struct {
+		flags []string
+		exp   bool
+	}{
+		{nil, false},
+		{[]string{"compound"}, false},
+		{[]string{}, true},
+		{[]string{"compound", "dense"}, true},
+		{[]string{"super"}, true},
+	}
+
+	for _, test := range tests {
+		if got := compactStorage(test.flags); got != test.exp {
+			t.Errorf("%v: expected %v got %v", test.flags, test.exp, got)
+		}
+	}
+}
+
+func TestSchemaDDLOrder(t *testing.T) {
+	rows := testRows()
+	rows.keyspaces = append(rows.keyspaces, map[string]interface{}{
+		"keyspace_name": "system_auth",
+		"replication":   map[string]string{"class": "SimpleStrategy", "replication_factor": "1"},
+	})
+	// types are read in order of name, before the types they use
+	rows.types = append(rows.types, map[string]interface{}{
+		"keyspace_name": "shop",
+		"type_name":     "Customer",
+		"field_names":   []string{"addresses"},
+		"field_types":   []string{"frozen<list<frozen<contact>>>"},
+	}, map[string]interface{}{
+		"keyspace_name": "shop",
+		"type_name":     "contact",
+		"field_names":   []string{"home", "work"},
+		"field_types":   []string{"frozen<address>", "frozen<address>"},
+	})
+
+	ddl := SchemaDDL(parseSchema(rows))
+	if strings.Contains(ddl, "system_auth") {
+		t.Error("expected system keyspaces to be excluded")
+	}
+
+	order := []string{
+		"CREATE KEYSPACE shop ",
+		"CREATE TYPE shop.address ",
+		"CREATE TYPE shop.contact ",
+		`CREATE TYPE shop."Customer" `,
+		`CREATE TABLE shop."Customers" `,
+		"CREATE CUSTOM INDEX customers_name ",
+		"CREATE TABLE shop.orders ",
+		"CREATE INDEX orders_items ",
+		"CREATE MATERIALIZED VIEW shop.orders_by_total ",
+		"CREATE FUNCTION shop.plus(",
+		"CREATE AGGREGATE shop.sum_total(",
+	}
+
+	last := -1
+	for _, stmt := range order {
+		i := strings.Index(ddl, stmt)
+		if i < 0 {
+			t.Errorf("%s: not found", stmt)
+		} else if i < last {
+			t.Errorf("%s: out of order", stmt)
+		}
+		last = i
+	}
+}
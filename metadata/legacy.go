@@ -0,0 +1,449 @@
+package metadata
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gocql/gocql"
+)
+
+// legacySchemaReader reads the system.schema_* tables of Cassandra 2.x,
+// converting them to the layout of the system_schema tables.
+type legacySchemaReader struct {
+	db      *gocql.Session
+	version Version
+}
+
+// legacyRows are the rows of each legacy schema table.
+type legacyRows struct {
+	keyspaces      []map[string]interface{}
+	columnfamilies []map[string]interface{}
+	columns        []map[string]interface{}
+	usertypes      []map[string]interface{}
+	functions      []map[string]interface{}
+	aggregates     []map[string]interface{}
+}
+
+func (r *legacySchemaReader) read(keyspace string) ([]*Keyspace, error) {
+	rows := &legacyRows{}
+	tables := []schemaTable{
+		{"system.schema_keyspaces", &rows.keyspaces},
+		{"system.schema_columnfamilies", &rows.columnfamilies},
+		{"system.schema_columns", &rows.columns},
+	}
+
+	// user types were added in 2.1 and functions in 2.2
+	if r.version.AtLeast(2, 1) {
+		tables = append(tables, schemaTable{"system.schema_usertypes", &rows.usertypes})
+	}
+	if r.version.AtLeast(2, 2) {
+		tables = append(tables,
+			schemaTable{"system.schema_functions", &rows.functions},
+			schemaTable{"system.schema_aggregates", &rows.aggregates})
+	}
+
+	if err := readTables(r.db, keyspace, tables); err != nil {
+		return nil, err
+	}
+
+	return parseSchema(convertLegacy(rows)), nil
+}
+
+// jsonMap decodes the JSON object s, returning nil if it is not one.
+func jsonMap(s string) map[string]string {
+	var m map[string]string
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+// legacyTableOptions are the table options which are unchanged from the
+// legacy schema.
+var legacyTableOptions = [...]string{
+	"bloom_filter_fp_chance",
+	"comment",
+	"dclocal_read_repair_chance",
+	"default_time_to_live",
+	"gc_grace_seconds",
+	"max_index_interval",
+	"memtable_flush_period_in_ms",
+	"min_index_interval",
+	"read_repair_chance",
+	"speculative_retry",
+}
+
+// convertLegacy converts legacy schema rows to system_schema rows.
+func convertLegacy(legacy *legacyRows) *schemaRows {
+	rows := &schemaRows{}
+
+	for _, r := range legacy.keyspaces {
+		r := row(r)
+		replication := jsonMap(r.str("strategy_options"))
+		if replication == nil {
+			replication = make(map[string]string)
+		}
+		replication["class"] = r.str("strategy_class")
+
+		rows.keyspaces = append(rows.keyspaces, map[string]interface{}{
+			"keyspace_name":  r.str("keyspace_name"),
+			"durable_writes": r.boolean("durable_writes"),
+			"replication":    replication,
+		})
+	}
+
+	// the columns of compact storage tables without clustering columns are
+	// regular columns in the legacy schema but static columns since 3.0
+	staticCompact := make(map[tableKey]bool)
+	for _, r := range legacy.columnfamilies {
+		r := row(r)
+		flags := legacyFlags(r)
+		table := map[string]interface{}{
+			"keyspace_name": r.str("keyspace_name"),
+			"table_name":    r.str("columnfamily_name"),
+			"flags":         flags,
+		}
+		if compactStorage(flags) && !hasFlag(flags, "dense") {
+			staticCompact[tableKey{r.str("keyspace_name"), r.str("columnfamily_name")}] = true
+		}
+		for _, name := range legacyTableOptions {
+			if v, ok := r[name]; ok {
+				table[name] = v
+			}
+		}
+
+		// caching is JSON from 2.1, before that it is a single value
+		if caching := jsonMap(r.str("caching")); caching != nil {
+			table["caching"] = caching
+		} else if caching := r.str("caching"); caching != "" {
+			table["caching"] = caching
+		}
+
+		compaction := jsonMap(r.str("compaction_strategy_options"))
+		if compaction == nil {
+			compaction = make(map[string]string)
+		}
+		compaction["class"] = r.str("compaction_strategy_class")
+		table["compaction"] = compaction
+
+		if compression := jsonMap(r.str("compression_parameters")); compression != nil {
+			table["compression"] = compression
+		}
+
+		rows.tables = append(rows.tables, table)
+	}
+
+	for _, r := range legacy.columns {
+		r := row(r)
+		kind := r.str("type")
+		switch kind {
+		case "clustering_key":
+			kind = string(Clustering)
+		case "compact_value":
+			continue
+		case string(Regular):
+			if staticCompact[tableKey{r.str("keyspace_name"), r.str("columnfamily_name")}] {
+				kind = string(Static)
+			}
+		}
+
+		typ, reversed := legacyType(r.str("validator"))
+		order := "none"
+		if kind == string(Clustering) {
+			order = "asc"
+			if reversed {
+				order = "desc"
+			}
+		}
+
+		position := r.int("component_index")
+		if kind == string(Regular) || kind == string(Static) {
+			position = -1
+		}
+
+		col := map[string]interface{}{
+			"keyspace_name":    r.str("keyspace_name"),
+			"table_name":       r.str("columnfamily_name"),
+			"column_name":      r.str("column_name"),
+			"kind":             kind,
+			"type":             typ,
+			"position":         position,
+			"clustering_order": order,
+		}
+		rows.columns = append(rows.columns, col)
+
+		if name := r.str("index_name"); name != "" {
+			rows.indexes = append(rows.indexes, map[string]interface{}{
+				"keyspace_name": col["keyspace_name"],
+				"table_name":    col["table_name"],
+				"index_name":    name,
+				"kind":          r.str("index_type"),
+				"options":       legacyIndexOptions(r.str("index_options"), r.str("column_name"), typ),
+			})
+		}
+	}
+
+	for _, r := range legacy.usertypes {
+		r := row(r)
+		rows.types = append(rows.types, map[string]interface{}{
+			"keyspace_name": r.str("keyspace_name"),
+			"type_name":     r.str("type_name"),
+			"field_names":   r.strings("field_names"),
+			"field_types":   legacyTypes(r.strings("field_types")),
+		})
+	}
+
+	for _, r := range legacy.functions {
+		r := row(r)
+		returnType, _ := legacyType(r.str("return_type"))
+		rows.functions = append(rows.functions, map[string]interface{}{
+			"keyspace_name":        r.str("keyspace_name"),
+			"function_name":        r.str("function_name"),
+			"argument_names":       r.strings("argument_names"),
+			"argument_types":       legacyTypes(r.strings("argument_types")),
+			"return_type":          returnType,
+			"language":             r.str("language"),
+			"body":                 r.str("body"),
+			"called_on_null_input": r.boolean("called_on_null_input"),
+		})
+	}
+
+	for _, r := range legacy.aggregates {
+		r := row(r)
+		returnType, _ := legacyType(r.str("return_type"))
+		stateType, _ := legacyType(r.str("state_type"))
+		initCond, _ := r["initcond"].([]byte)
+		rows.aggregates = append(rows.aggregates, map[string]interface{}{
+			"keyspace_name":  r.str("keyspace_name"),
+			"aggregate_name": r.str("aggregate_name"),
+			"argument_types": legacyTypes(r.strings("argument_types")),
+			"state_func":     r.str("state_func"),
+			"state_type":     stateType,
+			"final_func":     r.str("final_func"),
+			"return_type":    returnType,
+			"initcond":       legacyInitCond(initCond, stateType),
+		})
+	}
+
+	return rows
+}
+
+// legacyFlags returns the system_schema flags of a legacy table.
+func legacyFlags(r row) []string {
+	flags := []string{}
+	if strings.Contains(r.str("comparator"), "CompositeType") {
+		flags = append(flags, "compound")
+	}
+	if r.boolean("is_dense") {
+		flags = append(flags, "dense")
+	}
+	if strings.EqualFold(r.str("type"), "super") {
+		flags = append(flags, "super")
+	}
+	if strings.Contains(r.str("default_validator"), "CounterColumnType") {
+		flags = append(flags, "counter")
+	}
+	return flags
+}
+
+// legacyIndexOptions returns the options of an index on column with the
+// target which is implicit in the legacy schema.
+func legacyIndexOptions(options, column, typ string) map[string]string {
+	opts := jsonMap(options)
+	if opts == nil {
+		opts = make(map[string]string)
+	}
+
+	target := column
+	if _, ok := opts["index_keys"]; ok {
+		target = "keys(" + column + ")"
+	} else if _, ok := opts["index_keys_and_values"]; ok {
+		target = "entries(" + column + ")"
+	} else if strings.HasPrefix(typ, "frozen<") {
+		target = "full(" + column + ")"
+	} else if strings.HasPrefix(typ, "list<") || strings.HasPrefix(typ, "set<") || strings.HasPrefix(typ, "map<") {
+		target = "values(" + column + ")"
+	}
+
+	delete(opts, "index_keys")
+	delete(opts, "index_keys_and_values")
+	opts["target"] = target
+	return opts
+}
+
+// legacyInitCond formats the serialized initial condition of an aggregate
+// as a CQL literal of typ. Only numbers, booleans and strings can be
+// formatted, anything else is written as a blob.
+func legacyInitCond(data []byte, typ string) string {
+	if data == nil {
+		return ""
+	}
+
+	natives := map[string]gocql.Type{
+		"ascii":    gocql.TypeAscii,
+		"bigint":   gocql.TypeBigInt,
+		"boolean":  gocql.TypeBoolean,
+		"double":   gocql.TypeDouble,
+		"float":    gocql.TypeFloat,
+		"int":      gocql.TypeInt,
+		"smallint": gocql.TypeSmallInt,
+		"text":     gocql.TypeVarchar,
+		"tinyint":  gocql.TypeTinyInt,
+		"varchar":  gocql.TypeVarchar,
+		"varint":   gocql.TypeVarint,
+	}
+
+	blob := "0x" + hex.EncodeToString(data)
+	native, ok := natives[typ]
+	if !ok {
+		return blob
+	}
+
+	info := gocql.NewNativeType(3, native, "")
+	v, err := info.NewWithError()
+	if err != nil {
+		return blob
+	}
+	if err := gocql.Unmarshal(info, data, v); err != nil {
+		return blob
+	}
+
+	switch v := reflect.ValueOf(v).Elem().Interface().(type) {
+	case string:
+		return quoteString(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func legacyTypes(classes []string) []string {
+	types := make([]string, len(classes))
+	for i, class := range classes {
+		types[i], _ = legacyType(class)
+	}
+	return types
+}
+
+// legacyType converts the marshal class of a type in the legacy schema to
+// its CQL type, reversed is set if the type is in descending order.
+func legacyType(class string) (typ string, reversed bool) {
+	c, _ := parseMarshalClass(class, 0)
+	return c.cql(false), c.short() == "ReversedType"
+}
+
+// marshalClass is a parsed marshal class, for example
+// org.apache.cassandra.db.marshal.MapType(UTF8Type,Int32Type).
+type marshalClass struct {
+	name   string
+	params []*marshalClass
+	// raw is the class as written, including its parameters
+	raw string
+}
+
+func parseMarshalClass(s string, pos int) (*marshalClass, int) {
+	start := pos
+	for pos < len(s) && !strings.ContainsRune("(),:", rune(s[pos])) {
+		pos++
+	}
+
+	if pos < len(s) && s[pos] == ':' {
+		// the hex encoded field name of a UserType precedes the field's class
+		return parseMarshalClass(s, pos+1)
+	}
+
+	c := &marshalClass{name: strings.TrimSpace(s[start:pos])}
+	if pos < len(s) && s[pos] == '(' {
+		pos++
+		for pos < len(s) && s[pos] != ')' {
+			var param *marshalClass
+			param, pos = parseMarshalClass(s, pos)
+			c.params = append(c.params, param)
+			if pos < len(s) && s[pos] == ',' {
+				pos++
+			}
+		}
+		if pos < len(s) {
+			pos++
+		}
+	}
+
+	c.raw = s[start:pos]
+	return c, pos
+}
+
+func (c *marshalClass) short() string {
+	return c.name[strings.LastIndex(c.name, ".")+1:]
+}
+
+// legacyNatives are the CQL types of the marshal classes without parameters.
+var legacyNatives = map[string]string{
+	"AsciiType":         "ascii",
+	"BooleanType":       "boolean",
+	"ByteType":          "tinyint",
+	"BytesType":         "blob",
+	"CounterColumnType": "counter",
+	"DateType":          "timestamp",
+	"DecimalType":       "decimal",
+	"DoubleType":        "double",
+	"DurationType":      "duration",
+	"EmptyType":         "empty",
+	"FloatType":         "float",
+	"InetAddressType":   "inet",
+	"Int32Type":         "int",
+	"IntegerType":       "varint",
+	"LexicalUUIDType":   "uuid",
+	"LongType":          "bigint",
+	"ShortType":         "smallint",
+	"SimpleDateType":    "date",
+	"TimeType":          "time",
+	"TimeUUIDType":      "timeuuid",
+	"TimestampType":     "timestamp",
+	"UTF8Type":          "text",
+	"UUIDType":          "uuid",
+}
+
+// cql returns the CQL type of c, tuples and user types must be frozen in
+// Cassandra 2.x so they are frozen if they are not already.
+func (c *marshalClass) cql(frozen bool) string {
+	param := func(i int) string {
+		return c.params[i].cql(false)
+	}
+	freeze := func(t string) string {
+		if frozen {
+			return t
+		}
+		return "frozen<" + t + ">"
+	}
+
+	switch short := c.short(); {
+	case short == "ReversedType" && len(c.params) == 1:
+		return c.params[0].cql(frozen)
+	case short == "FrozenType" && len(c.params) == 1:
+		return "frozen<" + c.params[0].cql(true) + ">"
+	case (short == "ListType" || short == "SetType") && len(c.params) == 1:
+		return strings.ToLower(strings.TrimSuffix(short, "Type")) + "<" + param(0) + ">"
+	case short == "MapType" && len(c.params) == 2:
+		return "map<" + param(0) + ", " + param(1) + ">"
+	case short == "TupleType" && len(c.params) > 0:
+		types := make([]string, len(c.params))
+		for i := range c.params {
+			types[i] = param(i)
+		}
+		return freeze("tuple<" + strings.Join(types, ", ") + ">")
+	case short == "UserType" && len(c.params) >= 2:
+		name, err := hex.DecodeString(c.params[1].name)
+		if err == nil {
+			return freeze(QuoteIdentifier(string(name)))
+		}
+	case len(c.params) == 0:
+		if t, ok := legacyNatives[short]; ok {
+			return t
+		}
+	}
+
+	// custom types are written as the quoted class name
+	return quoteString(c.raw)
+}
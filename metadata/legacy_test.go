@@ -0,0 +1,202 @@
+package metadata
+
+import "testing"
+
+func TestLegacyType(t *testing.T) {
+	const marshal = "org.apache.cassandra.db.marshal."
+	tests := [...]struct {
+		class    string
+		exp      string
+		reversed bool
+	}{
+		{marshal + "Int32Type", "int", false},
+		{marshal + "ReversedType(" + marshal + "TimestampType)", "timestamp", true},
+		{marshal + "MapType(" + marshal + "UTF8Type," + marshal + "LongType)", "map<text, bigint>", false},
+		{marshal + "FrozenType(" + marshal + "ListType(" + marshal + "UUIDType))", "frozen<list<uuid>>", false},
+		{marshal + "SetType(" + marshal + "FrozenType(" + marshal + "TupleType(" + marshal + "Int32Type," + marshal + "AsciiType)))",
+			"set<frozen<tuple<int, ascii>>>", false},
+		{marshal + "TupleType(" + marshal + "Int32Type)", "frozen<tuple<int>>", false},
+		{marshal + "UserType(shop,61646472657373,737472656574:" + marshal + "UTF8Type,706f7374636f6465:" + marshal + "UTF8Type)",
+			"frozen<address>", false},
+		{marshal + "FrozenType(" + marshal + "UserType(shop,4164647265737373,737472656574:" + marshal + "UTF8Type))",
+			`frozen<"Addresss">`, false},
+		{"com.example.CustomType", "'com.example.CustomType'", false},
+		{marshal + "ListType(com.example.CustomType)", "list<'com.example.CustomType'>", false},
+	}
+
+	for _, test := range tests {
+		typ, reversed := legacyType(test.class)
+		if typ != test.exp || reversed != test.reversed {
+			t.Errorf("%s: expected %s reversed %v got %s reversed %v", test.class, test.exp, test.reversed, typ, reversed)
+		}
+	}
+}
+
+func TestConvertLegacy(t *testing.T) {
+	const marshal = "org.apache.cassandra.db.marshal."
+	column := func(name, kind, validator string, index int) map[string]interface{} {
+		return map[string]interface{}{
+			"keyspace_name":     "shop",
+			"columnfamily_name": "orders",
+			"column_name":       name,
+			"type":              kind,
+			"validator":         validator,
+			"component_index":   index,
+		}
+	}
+
+	items := column("items", "regular", marshal+"MapType("+marshal+"UTF8Type,"+marshal+"Int32Type)", 2)
+	items["index_name"] = "orders_items"
+	items["index_type"] = "COMPOSITES"
+	items["index_options"] = `{"index_keys": ""}`
+
+	keyspaces := parseSchema(convertLegacy(&legacyRows{
+		keyspaces: []map[string]interface{}{
+			{
+				"keyspace_name":    "shop",
+				"durable_writes":   true,
+				"strategy_class":   "org.apache.cassandra.locator.SimpleStrategy",
+				"strategy_options": `{"replication_factor":"1"}`,
+			},
+		},
+		columnfamilies: []map[string]interface{}{
+			{
+				"keyspace_name":               "shop",
+				"columnfamily_name":           "orders",
+				"comparator":                  marshal + "CompositeType(" + marshal + "TimeUUIDType," + marshal + "UTF8Type)",
+				"is_dense":                    false,
+				"type":                        "Standard",
+				"caching":                     `{"keys":"ALL", "rows_per_partition":"NONE"}`,
+				"comment":                     "",
+				"compaction_strategy_class":   "org.apache.cassandra.db.compaction.SizeTieredCompactionStrategy",
+				"compaction_strategy_options": `{}`,
+				"compression_parameters":      `{"sstable_compression":"org.apache.cassandra.io.compress.LZ4Compressor"}`,
+				"gc_grace_seconds":            864000,
+				"cf_id":                       "ignored",
+			},
+		},
+		columns: []map[string]interface{}{
+			column("customer", "partition_key", marshal+"UUIDType", 0),
+			column("placed", "clustering_key", marshal+"ReversedType("+marshal+"TimestampType)", 0),
+			items,
+			column("", "compact_value", marshal+"BytesType", 0),
+		},
+		usertypes: []map[string]interface{}{
+			{
+				"keyspace_name": "shop",
+				"type_name":     "address",
+				"field_names":   []string{"street"},
+				"field_types":   []string{marshal + "UTF8Type"},
+			},
+		},
+		aggregates: []map[string]interface{}{
+			{
+				"keyspace_name":  "shop",
+				"aggregate_name": "total",
+				"argument_types": []string{marshal + "Int32Type"},
+				"state_func":     "plus",
+				"state_type":     marshal + "Int32Type",
+				"return_type":    marshal + "Int32Type",
+				"initcond":       []byte{0, 0, 0, 42},
+			},
+		},
+	}))
+
+	if len(keyspaces) != 1 {
+		t.Fatalf("expected 1 keyspace got %d", len(keyspaces))
+	}
+
+	exp := `CREATE KEYSPACE shop WITH replication = {'class': 'org.apache.cassandra.locator.SimpleStrategy', 'replication_factor': '1'} AND durable_writes = true;
+
+CREATE TYPE shop.address (
+    street text
+);
+
+CREATE TABLE shop.orders (
+    customer uuid,
+    placed timestamp,
+    items map<text, int>,
+    PRIMARY KEY (customer, placed)
+) WITH CLUSTERING ORDER BY (placed DESC)
+    AND caching = {'keys': 'ALL', 'rows_per_partition': 'NONE'}
+    AND comment = ''
+    AND compaction = {'class': 'org.apache.cassandra.db.compaction.SizeTieredCompactionStrategy'}
+    AND compression = {'sstable_compression': 'org.apache.cassandra.io.compress.LZ4Compressor'}
+    AND gc_grace_seconds = 864000;
+
+CREATE INDEX orders_items ON shop.orders (keys(items));
+
+CREATE AGGREGATE shop.total(int)
+    SFUNC plus
+    STYPE int
+    INITCOND 42;`
+	if ddl := keyspaces[0].DDL(); ddl != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, ddl)
+	}
+}
+
+func TestConvertLegacyStaticCompact(t *testing.T) {
+	const marshal = "org.apache.cassandra.db.marshal."
+	keyspaces := parseSchema(convertLegacy(&legacyRows{
+		keyspaces: []map[string]interface{}{
+			{"keyspace_name": "shop", "strategy_class": "SimpleStrategy", "strategy_options": `{"replication_factor":"1"}`},
+		},
+		columnfamilies: []map[string]interface{}{
+			{"keyspace_name": "shop", "columnfamily_name": "settings", "comparator": marshal + "UTF8Type", "is_dense": false,
+				"compaction_strategy_class": "org.apache.cassandra.db.compaction.LeveledCompactionStrategy"},
+		},
+		columns: []map[string]interface{}{
+			{"keyspace_name": "shop", "columnfamily_name": "settings", "column_name": "name", "type": "partition_key",
+				"validator": marshal + "UTF8Type", "component_index": nil},
+			{"keyspace_name": "shop", "columnfamily_name": "settings", "column_name": "enabled", "type": "regular",
+				"validator": marshal + "BooleanType", "component_index": nil},
+		},
+	}))
+
+	exp := `CREATE TABLE shop.settings (
+    name text PRIMARY KEY,
+    enabled boolean
+) WITH COMPACT STORAGE
+    AND compaction = {'class': 'org.apache.cassandra.db.compaction.LeveledCompactionStrategy'};`
+	if got := keyspaces[0].Table("settings").CQL(); got != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, got)
+	}
+}
+
+func TestLegacyInitCond(t *testing.T) {
+	tests := [...]struct {
+		data []byte
+		typ  string
+		exp  string
+	}{
+		{nil, "int", ""},
+		{[]byte("it's"), "text", "'it''s'"},
+		{[]byte{1}, "boolean", "true"},
+		{[]byte{0, 1}, "map<int, int>", "0x0001"},
+	}
+
+	for _, test := range tests {
+		if got := legacyInitCond(test.data, test.typ); got != test.exp {
+			t.Errorf("%s %x: expected %s got %s", test.typ, test.data, test.exp, got)
+		}
+	}
+}
+
+func TestNewSchemaReader(t *testing.T) {
+	tests := [...]struct {
+		version Version
+		legacy  bool
+	}{
+		{Version{2, 1, 9}, true},
+		{Version{2, 2, 0}, true},
+		{Version{3, 0, 0}, false},
+		{Version{4, 1, 3}, false},
+	}
+
+	for _, test := range tests {
+		_, legacy := newSchemaReader(nil, test.version).(*legacySchemaReader)
+		if legacy != test.legacy {
+			t.Errorf("%v: expected legacy reader %v got %v", test.version, test.legacy, legacy)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package metadata
+
+import (
+	"fmt"
+
+	"github.com/gocql/gocql"
+)
+
+// schemaReader reads the schema from the schema tables of a version of
+// Cassandra.
+type schemaReader interface {
+	// read returns the schema of keyspace, or of every keyspace if it is
+	// empty.
+	read(keyspace string) ([]*Keyspace, error)
+}
+
+// newSchemaReader returns the reader for the schema tables of version.
+func newSchemaReader(db *gocql.Session, version Version) schemaReader {
+	if version.AtLeast(3, 0) {
+		return &systemSchemaReader{db: db}
+	}
+	return &legacySchemaReader{db: db, version: version}
+}
+
+// reader returns the schema reader for the connected cluster.
+func (c *Cassandra) reader() (schemaReader, error) {
+	if c.schemaReader != nil {
+		return c.schemaReader, nil
+	}
+
+	info, err := c.ClusterMeta()
+	if err != nil {
+		return nil, err
+	}
+
+	c.schemaReader = newSchemaReader(c.db, info.Version)
+	return c.schemaReader, nil
+}
+
+// schemaTable is a schema table and where its rows are read to.
+type schemaTable struct {
+	name string
+	rows *[]map[string]interface{}
+}
+
+// readTables reads the rows of each table, restricted to keyspace if it is
+// set.
+func readTables(db *gocql.Session, keyspace string, tables []schemaTable) error {
+	where, values := "", []interface{}(nil)
+	if keyspace != "" {
+		where, values = " WHERE keyspace_name = ?", []interface{}{keyspace}
+	}
+
+	for _, table := range tables {
+		var err error
+		*table.rows, err = db.Query("SELECT * FROM "+table.name+where, values...).Iter().SliceMap()
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %v", table.name, err)
+		}
+	}
+
+	return nil
+}
+
+// systemSchemaReader reads the system_schema tables of Cassandra 3.0 and
+// later.
+type systemSchemaReader struct {
+	db *gocql.Session
+}
+
+func (r *systemSchemaReader) read(keyspace string) ([]*Keyspace, error) {
+	rows := &schemaRows{}
+	err := readTables(r.db, keyspace, []schemaTable{
+		{"system_schema.keyspaces", &rows.keyspaces},
+		{"system_schema.tables", &rows.tables},
+		{"system_schema.columns", &rows.columns},
+		{"system_schema.views", &rows.views},
+		{"system_schema.indexes", &rows.indexes},
+		{"system_schema.types", &rows.types},
+		{"system_schema.functions", &rows.functions},
+		{"system_schema.aggregates", &rows.aggregates},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSchema(rows), nil
+}
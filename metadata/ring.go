@@ -0,0 +1,143 @@
+package metadata
+
+import (
+	"math/big"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Host is a node in the cluster and the tokens it owns.
+type Host struct {
+	Address    net.IP
+	DataCenter string
+	Tokens     []string
+}
+
+// Hosts returns the node connected to followed by its peers.
+func (c *Cassandra) Hosts() ([]*Host, error) {
+	local := &Host{}
+	err := c.db.Query("SELECT listen_address, data_center, tokens FROM system.local").Scan(
+		&local.Address, &local.DataCenter, &local.Tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := []*Host{local}
+	iter := c.db.Query("SELECT peer, data_center, tokens FROM system.peers").Iter()
+	for {
+		peer := &Host{}
+		if !iter.Scan(&peer.Address, &peer.DataCenter, &peer.Tokens) {
+			break
+		}
+		hosts = append(hosts, peer)
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
+
+// TokenOwner is a token in the ring and the hosts which replicate the range
+// of tokens ending with it.
+type TokenOwner struct {
+	Token    string
+	Replicas []*Host
+}
+
+// Ownership returns the replicas of each token in the ring formed by hosts
+// for a keyspace with replication, in token order.
+func Ownership(hosts []*Host, replication map[string]string) []TokenOwner {
+	type entry struct {
+		token string
+		value *big.Int
+		host  *Host
+	}
+
+	var ring []entry
+	numeric := true
+	for _, h := range hosts {
+		for _, token := range h.Tokens {
+			v, ok := new(big.Int).SetString(token, 10)
+			numeric = numeric && ok
+			ring = append(ring, entry{token, v, h})
+		}
+	}
+
+	// tokens are numbers except for the ordered partitioners
+	sort.Slice(ring, func(i, j int) bool {
+		if numeric {
+			return ring[i].value.Cmp(ring[j].value) < 0
+		}
+		return ring[i].token < ring[j].token
+	})
+
+	factors := replicationFactors(hosts, replication)
+	want := 0
+	for dc, n := range factors {
+		available := 0
+		for _, h := range hosts {
+			if dc == "" || h.DataCenter == dc {
+				available++
+			}
+		}
+		if n > available {
+			n = available
+		}
+		want += n
+	}
+
+	owners := make([]TokenOwner, len(ring))
+	for i := range ring {
+		owners[i].Token = ring[i].token
+
+		placed := make(map[string]int)
+		seen := make(map[*Host]bool)
+		for j := 0; j < len(ring) && len(owners[i].Replicas) < want; j++ {
+			h := ring[(i+j)%len(ring)].host
+			dc := h.DataCenter
+			if _, ok := factors[dc]; !ok {
+				dc = ""
+			}
+
+			if seen[h] || placed[dc] >= factors[dc] {
+				continue
+			}
+
+			seen[h] = true
+			placed[dc]++
+			owners[i].Replicas = append(owners[i].Replicas, h)
+		}
+	}
+
+	return owners
+}
+
+// replicationFactors returns the number of replicas in each data center for
+// replication, the empty data center is used when replicas are placed
+// without regard to data center.
+func replicationFactors(hosts []*Host, replication map[string]string) map[string]int {
+	class := replication["class"]
+	class = class[strings.LastIndex(class, ".")+1:]
+
+	factors := make(map[string]int)
+	switch class {
+	case "SimpleStrategy":
+		factors[""], _ = strconv.Atoi(replication["replication_factor"])
+	case "NetworkTopologyStrategy":
+		for dc, rf := range replication {
+			if n, err := strconv.Atoi(rf); err == nil && dc != "class" {
+				factors[dc] = n
+			}
+		}
+	case "EverywhereStrategy":
+		factors[""] = len(hosts)
+	default:
+		// LocalStrategy and anything unknown is only owned by the token's host
+		factors[""] = 1
+	}
+
+	return factors
+}
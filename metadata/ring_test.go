@@ -0,0 +1,67 @@
+package metadata
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestOwnership(t *testing.T) {
+	host := func(addr, dc string, tokens ...string) *Host {
+		return &Host{Address: net.ParseIP(addr), DataCenter: dc, Tokens: tokens}
+	}
+	hosts := []*Host{
+		host("10.0.0.1", "dc1", "-100", "200"),
+		host("10.0.0.2", "dc1", "0"),
+		host("10.0.0.3", "dc2", "-9223372036854775808", "100"),
+	}
+
+	format := func(owners []TokenOwner) string {
+		var lines []string
+		for _, owner := range owners {
+			var addrs []string
+			for _, h := range owner.Replicas {
+				addrs = append(addrs, h.Address.String())
+			}
+			lines = append(lines, owner.Token+" "+strings.Join(addrs, ","))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	tests := [...]struct {
+		name        string
+		replication map[string]string
+		exp         string
+	}{
+		{"simple", map[string]string{"class": "org.apache.cassandra.locator.SimpleStrategy", "replication_factor": "2"},
+			`-9223372036854775808 10.0.0.3,10.0.0.1
+-100 10.0.0.1,10.0.0.2
+0 10.0.0.2,10.0.0.3
+100 10.0.0.3,10.0.0.1
+200 10.0.0.1,10.0.0.3`},
+		{"network topology", map[string]string{"class": "NetworkTopologyStrategy", "dc1": "2", "dc3": "1"},
+			`-9223372036854775808 10.0.0.1,10.0.0.2
+-100 10.0.0.1,10.0.0.2
+0 10.0.0.2,10.0.0.1
+100 10.0.0.1,10.0.0.2
+200 10.0.0.1,10.0.0.2`},
+		{"replication factor above hosts", map[string]string{"class": "SimpleStrategy", "replication_factor": "5"},
+			`-9223372036854775808 10.0.0.3,10.0.0.1,10.0.0.2
+-100 10.0.0.1,10.0.0.2,10.0.0.3
+0 10.0.0.2,10.0.0.3,10.0.0.1
+100 10.0.0.3,10.0.0.1,10.0.0.2
+200 10.0.0.1,10.0.0.3,10.0.0.2`},
+		{"local", map[string]string{"class": "org.apache.cassandra.locator.LocalStrategy"},
+			`-9223372036854775808 10.0.0.3
+-100 10.0.0.1
+0 10.0.0.2
+100 10.0.0.3
+200 10.0.0.1`},
+	}
+
+	for _, test := range tests {
+		if got := format(Ownership(hosts, test.replication)); got != test.exp {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", test.name, test.exp, got)
+		}
+	}
+}
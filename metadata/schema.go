@@ -0,0 +1,491 @@
+package metadata
+
+import "sort"
+
+// Keyspace is the schema of a keyspace and everything defined in it, each
+// kind of object is ordered by name.
+type Keyspace struct {
+	Name          string
+	DurableWrites bool
+	// Replication is the replication strategy class and its options
+	Replication map[string]string
+
+	Types      []*Type
+	Tables     []*Table
+	Views      []*View
+	Functions  []*Function
+	Aggregates []*Aggregate
+}
+
+// systemKeyspaces are the keyspaces created and managed by Cassandra.
+var systemKeyspaces = map[string]bool{
+	"system":                true,
+	"system_auth":           true,
+	"system_distributed":    true,
+	"system_schema":         true,
+	"system_traces":         true,
+	"system_views":          true,
+	"system_virtual_schema": true,
+}
+
+// System reports whether k is managed by Cassandra rather than created by a
+// user.
+func (k *Keyspace) System() bool {
+	return systemKeyspaces[k.Name]
+}
+
+// Table returns the table called name or nil if there is none.
+func (k *Keyspace) Table(name string) *Table {
+	for _, t := range k.Tables {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Column returns the column called name or nil if there is none.
+func (t *Table) Column(name string) *Column {
+	for _, col := range t.Columns {
+		if col.Name == name {
+			return col
+		}
+	}
+	return nil
+}
+
+// View returns the materialized view called name or nil if there is none.
+func (k *Keyspace) View(name string) *View {
+	for _, v := range k.Views {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Type returns the user defined type called name or nil if there is none.
+func (k *Keyspace) Type(name string) *Type {
+	for _, t := range k.Types {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Index returns the secondary index called name or nil if there is none.
+func (k *Keyspace) Index(name string) *Index {
+	for _, t := range k.Tables {
+		for _, idx := range t.Indexes {
+			if idx.Name == name {
+				return idx
+			}
+		}
+	}
+	return nil
+}
+
+// FunctionsNamed returns every overload of the function called name.
+func (k *Keyspace) FunctionsNamed(name string) []*Function {
+	var fns []*Function
+	for _, fn := range k.Functions {
+		if fn.Name == name {
+			fns = append(fns, fn)
+		}
+	}
+	return fns
+}
+
+// AggregatesNamed returns every overload of the aggregate called name.
+func (k *Keyspace) AggregatesNamed(name string) []*Aggregate {
+	var aggs []*Aggregate
+	for _, agg := range k.Aggregates {
+		if agg.Name == name {
+			aggs = append(aggs, agg)
+		}
+	}
+	return aggs
+}
+
+type ColumnKind string
+
+const (
+	PartitionKey ColumnKind = "partition_key"
+	Clustering   ColumnKind = "clustering"
+	Regular      ColumnKind = "regular"
+	Static       ColumnKind = "static"
+)
+
+type Column struct {
+	Name string
+	// Type is the CQL type of the column, for example frozen<list<int>>
+	Type string
+	Kind ColumnKind
+	// Position orders the partition key and clustering columns
+	Position int
+	// Desc is set for clustering columns in descending order
+	Desc bool
+}
+
+// Options are the options of a table or view by name, values are the types
+// returned by the driver for the system_schema columns.
+type Options map[string]interface{}
+
+type Table struct {
+	Keyspace string
+	Name     string
+	// Columns are ordered by the partition key, the clustering columns and
+	// then the remaining columns by name
+	Columns           []*Column
+	PartitionKey      []*Column
+	ClusteringColumns []*Column
+	CompactStorage    bool
+	Options           Options
+
+	Indexes []*Index
+	// Views are the materialized views of the table
+	Views []*View
+}
+
+// View is a materialized view, the embedded Table describes the columns and
+// options of the view itself.
+type View struct {
+	Table
+	BaseTable         string
+	IncludeAllColumns bool
+	WhereClause       string
+}
+
+type Index struct {
+	Keyspace string
+	Table    string
+	Name     string
+	// Kind is KEYS, COMPOSITES or CUSTOM
+	Kind    string
+	Options map[string]string
+}
+
+type Type struct {
+	Keyspace   string
+	Name       string
+	FieldNames []string
+	FieldTypes []string
+}
+
+type Function struct {
+	Keyspace          string
+	Name              string
+	ArgumentNames     []string
+	ArgumentTypes     []string
+	ReturnType        string
+	Language          string
+	Body              string
+	CalledOnNullInput bool
+}
+
+type Aggregate struct {
+	Keyspace      string
+	Name          string
+	ArgumentTypes []string
+	StateFunc     string
+	StateType     string
+	FinalFunc     string
+	ReturnType    string
+	// InitCond is a CQL literal or empty if there is no initial condition
+	InitCond string
+}
+
+// schemaRows are the rows of each system_schema table.
+type schemaRows struct {
+	keyspaces  []map[string]interface{}
+	tables     []map[string]interface{}
+	columns    []map[string]interface{}
+	views      []map[string]interface{}
+	indexes    []map[string]interface{}
+	types      []map[string]interface{}
+	functions  []map[string]interface{}
+	aggregates []map[string]interface{}
+}
+
+// row is a row read with MapScan, missing or null values are read as the
+// zero value.
+type row map[string]interface{}
+
+func (r row) str(name string) string {
+	s, _ := r[name].(string)
+	return s
+}
+
+func (r row) boolean(name string) bool {
+	b, _ := r[name].(bool)
+	return b
+}
+
+func (r row) int(name string) int {
+	n, _ := r[name].(int)
+	return n
+}
+
+func (r row) strings(name string) []string {
+	s, _ := r[name].([]string)
+	return s
+}
+
+func (r row) stringMap(name string) map[string]string {
+	m, _ := r[name].(map[string]string)
+	return m
+}
+
+// tableKey identifies a table or view within the schema.
+type tableKey struct {
+	keyspace, name string
+}
+
+func parseSchema(rows *schemaRows) []*Keyspace {
+	var keyspaces []*Keyspace
+	byName := make(map[string]*Keyspace)
+	for _, r := range rows.keyspaces {
+		r := row(r)
+		ks := &Keyspace{
+			Name:          r.str("keyspace_name"),
+			DurableWrites: r.boolean("durable_writes"),
+			Replication:   r.stringMap("replication"),
+		}
+		keyspaces = append(keyspaces, ks)
+		byName[ks.Name] = ks
+	}
+
+	columns := make(map[tableKey][]*Column)
+	for _, r := range rows.columns {
+		r := row(r)
+		col := &Column{
+			Name:     r.str("column_name"),
+			Type:     r.str("type"),
+			Kind:     ColumnKind(r.str("kind")),
+			Position: r.int("position"),
+			Desc:     r.str("clustering_order") == "desc",
+		}
+		if col.Name == "" {
+			// the hidden value column of compact storage tables
+			continue
+		}
+
+		key := tableKey{r.str("keyspace_name"), r.str("table_name")}
+		columns[key] = append(columns[key], col)
+	}
+
+	tables := make(map[tableKey]*Table)
+	for _, r := range rows.tables {
+		r := row(r)
+		ks, ok := byName[r.str("keyspace_name")]
+		if !ok {
+			continue
+		}
+
+		key := tableKey{ks.Name, r.str("table_name")}
+		flags := r.strings("flags")
+		t := &Table{
+			Keyspace:       key.keyspace,
+			Name:           key.name,
+			CompactStorage: compactStorage(flags),
+			Options:        options(r, "keyspace_name", "table_name", "id", "flags"),
+		}
+		if t.CompactStorage && !hasFlag(flags, "dense") {
+			t.setColumns(staticCompactColumns(columns[key]))
+		} else {
+			t.setColumns(columns[key])
+		}
+		ks.Tables = append(ks.Tables, t)
+		tables[key] = t
+	}
+
+	for _, r := range rows.views {
+		r := row(r)
+		ks, ok := byName[r.str("keyspace_name")]
+		if !ok {
+			continue
+		}
+
+		key := tableKey{ks.Name, r.str("view_name")}
+		v := &View{
+			Table: Table{
+				Keyspace: key.keyspace,
+				Name:     key.name,
+				Options: options(r, "keyspace_name", "view_name", "id", "base_table_id", "base_table_name",
+					"include_all_columns", "where_clause"),
+			},
+			BaseTable:         r.str("base_table_name"),
+			IncludeAllColumns: r.boolean("include_all_columns"),
+			WhereClause:       r.str("where_clause"),
+		}
+		v.setColumns(columns[key])
+		ks.Views = append(ks.Views, v)
+		if base, ok := tables[tableKey{ks.Name, v.BaseTable}]; ok {
+			base.Views = append(base.Views, v)
+		}
+	}
+
+	for _, r := range rows.indexes {
+		r := row(r)
+		key := tableKey{r.str("keyspace_name"), r.str("table_name")}
+		t, ok := tables[key]
+		if !ok {
+			continue
+		}
+
+		t.Indexes = append(t.Indexes, &Index{
+			Keyspace: key.keyspace,
+			Table:    key.name,
+			Name:     r.str("index_name"),
+			Kind:     r.str("kind"),
+			Options:  r.stringMap("options"),
+		})
+	}
+
+	for _, r := range rows.types {
+		r := row(r)
+		if ks, ok := byName[r.str("keyspace_name")]; ok {
+			ks.Types = append(ks.Types, &Type{
+				Keyspace:   ks.Name,
+				Name:       r.str("type_name"),
+				FieldNames: r.strings("field_names"),
+				FieldTypes: r.strings("field_types"),
+			})
+		}
+	}
+
+	for _, r := range rows.functions {
+		r := row(r)
+		if ks, ok := byName[r.str("keyspace_name")]; ok {
+			ks.Functions = append(ks.Functions, &Function{
+				Keyspace:          ks.Name,
+				Name:              r.str("function_name"),
+				ArgumentNames:     r.strings("argument_names"),
+				ArgumentTypes:     r.strings("argument_types"),
+				ReturnType:        r.str("return_type"),
+				Language:          r.str("language"),
+				Body:              r.str("body"),
+				CalledOnNullInput: r.boolean("called_on_null_input"),
+			})
+		}
+	}
+
+	for _, r := range rows.aggregates {
+		r := row(r)
+		if ks, ok := byName[r.str("keyspace_name")]; ok {
+			ks.Aggregates = append(ks.Aggregates, &Aggregate{
+				Keyspace:      ks.Name,
+				Name:          r.str("aggregate_name"),
+				ArgumentTypes: r.strings("argument_types"),
+				StateFunc:     r.str("state_func"),
+				StateType:     r.str("state_type"),
+				FinalFunc:     r.str("final_func"),
+				ReturnType:    r.str("return_type"),
+				InitCond:      r.str("initcond"),
+			})
+		}
+	}
+
+	sort.Slice(keyspaces, func(i, j int) bool { return keyspaces[i].Name < keyspaces[j].Name })
+	for _, ks := range keyspaces {
+		ks.sort()
+	}
+
+	return keyspaces
+}
+
+func (k *Keyspace) sort() {
+	sort.Slice(k.Types, func(i, j int) bool { return k.Types[i].Name < k.Types[j].Name })
+	sort.Slice(k.Tables, func(i, j int) bool { return k.Tables[i].Name < k.Tables[j].Name })
+	sort.Slice(k.Views, func(i, j int) bool { return k.Views[i].Name < k.Views[j].Name })
+	// overloads keep the order they were read in
+	sort.SliceStable(k.Functions, func(i, j int) bool { return k.Functions[i].Name < k.Functions[j].Name })
+	sort.SliceStable(k.Aggregates, func(i, j int) bool { return k.Aggregates[i].Name < k.Aggregates[j].Name })
+
+	for _, t := range k.Tables {
+		sort.Slice(t.Indexes, func(i, j int) bool { return t.Indexes[i].Name < t.Indexes[j].Name })
+		sort.Slice(t.Views, func(i, j int) bool { return t.Views[i].Name < t.Views[j].Name })
+	}
+}
+
+// setColumns orders columns by the primary key and then by name.
+func (t *Table) setColumns(columns []*Column) {
+	t.PartitionKey, t.ClusteringColumns = nil, nil
+	var others []*Column
+	for _, col := range columns {
+		switch col.Kind {
+		case PartitionKey:
+			t.PartitionKey = append(t.PartitionKey, col)
+		case Clustering:
+			t.ClusteringColumns = append(t.ClusteringColumns, col)
+		default:
+			others = append(others, col)
+		}
+	}
+
+	byPosition := func(cols []*Column) {
+		sort.Slice(cols, func(i, j int) bool { return cols[i].Position < cols[j].Position })
+	}
+	byPosition(t.PartitionKey)
+	byPosition(t.ClusteringColumns)
+	sort.Slice(others, func(i, j int) bool { return others[i].Name < others[j].Name })
+
+	t.Columns = nil
+	t.Columns = append(t.Columns, t.PartitionKey...)
+	t.Columns = append(t.Columns, t.ClusteringColumns...)
+	t.Columns = append(t.Columns, others...)
+}
+
+// compactStorage reports whether a table with flags was created WITH COMPACT
+// STORAGE.
+func compactStorage(flags []string) bool {
+	if flags == nil {
+		return false
+	}
+	return hasFlag(flags, "dense") || hasFlag(flags, "super") || !hasFlag(flags, "compound")
+}
+
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// staticCompactColumns returns the columns of a compact storage table without
+// clustering columns as they were created. Their columns are stored as static
+// columns alongside a hidden clustering column and regular value column.
+func staticCompactColumns(columns []*Column) []*Column {
+	var created []*Column
+	for _, col := range columns {
+		switch col.Kind {
+		case Clustering, Regular:
+			continue
+		case Static:
+			col.Kind = Regular
+		}
+		created = append(created, col)
+	}
+	return created
+}
+
+// options returns the values of r which are not null, excluding skip.
+func options(r row, skip ...string) Options {
+	opts := make(Options)
+	for name, v := range r {
+		opts[name] = v
+	}
+	for _, name := range skip {
+		delete(opts, name)
+	}
+
+	for name, v := range opts {
+		if v == nil {
+			delete(opts, name)
+		}
+	}
+	return opts
+}
@@ -0,0 +1,191 @@
+package metadata
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gocql/gocql"
+)
+
+// nativeTypes are the driver types of the CQL types which have no parameters.
+var nativeTypes = map[string]gocql.Type{
+	"ascii": gocql.TypeAscii, "bigint": gocql.TypeBigInt, "blob": gocql.TypeBlob, "boolean": gocql.TypeBoolean,
+	"counter": gocql.TypeCounter, "date": gocql.TypeDate, "decimal": gocql.TypeDecimal, "double": gocql.TypeDouble,
+	"duration": gocql.TypeDuration, "float": gocql.TypeFloat, "inet": gocql.TypeInet, "int": gocql.TypeInt,
+	"smallint": gocql.TypeSmallInt, "text": gocql.TypeText, "time": gocql.TypeTime, "timestamp": gocql.TypeTimestamp,
+	"timeuuid": gocql.TypeTimeUUID, "tinyint": gocql.TypeTinyInt, "uuid": gocql.TypeUUID,
+	"varchar": gocql.TypeVarchar, "varint": gocql.TypeVarint,
+}
+
+// TypeInfo returns the driver's description of typ, a CQL type as it is
+// written in the schema of k, for protocol version proto. User defined types
+// are looked up in k.
+func (k *Keyspace) TypeInfo(typ string, proto byte) (gocql.TypeInfo, error) {
+	p := &typeParser{ks: k, proto: proto, s: typ}
+	info, err := p.typ()
+	if err != nil {
+		return nil, fmt.Errorf("invalid type %s: %v", typ, err)
+	}
+
+	if p.space(); p.pos < len(p.s) {
+		return nil, fmt.Errorf("invalid type %s: unexpected %q", typ, p.s[p.pos:])
+	}
+	return info, nil
+}
+
+// typeParser parses a CQL type such as frozen<map<text, "Address">>.
+type typeParser struct {
+	ks    *Keyspace
+	proto byte
+	s     string
+	pos   int
+}
+
+func (p *typeParser) space() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// name reads the name of a type, quote is set to the quote character if it
+// is a quoted identifier or the string naming a custom type.
+func (p *typeParser) name() (name string, quote byte, err error) {
+	p.space()
+	if p.pos == len(p.s) {
+		return "", 0, fmt.Errorf("expected a type")
+	}
+
+	if quote = p.s[p.pos]; quote == '"' || quote == '\'' {
+		var b strings.Builder
+		for p.pos++; p.pos < len(p.s); p.pos++ {
+			if p.s[p.pos] == quote {
+				if p.pos+1 < len(p.s) && p.s[p.pos+1] == quote {
+					p.pos++
+				} else {
+					p.pos++
+					return b.String(), quote, nil
+				}
+			}
+			b.WriteByte(p.s[p.pos])
+		}
+		return "", 0, fmt.Errorf("unterminated %c", quote)
+	}
+
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			break
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		return "", 0, fmt.Errorf("expected a type at %q", p.s[p.pos:])
+	}
+	return strings.ToLower(p.s[start:p.pos]), 0, nil
+}
+
+// params reads the parameters of a type, ie <text, int>.
+func (p *typeParser) params() ([]gocql.TypeInfo, error) {
+	if p.space(); p.pos == len(p.s) || p.s[p.pos] != '<' {
+		return nil, fmt.Errorf("expected <")
+	}
+	p.pos++
+
+	var params []gocql.TypeInfo
+	for {
+		info, err := p.typ()
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, info)
+
+		p.space()
+		if p.pos == len(p.s) {
+			return nil, fmt.Errorf("expected >")
+		}
+		p.pos++
+		switch p.s[p.pos-1] {
+		case '>':
+			return params, nil
+		case ',':
+		default:
+			return nil, fmt.Errorf("unexpected %q", p.s[p.pos-1])
+		}
+	}
+}
+
+func (p *typeParser) typ() (gocql.TypeInfo, error) {
+	name, quote, err := p.name()
+	if err != nil {
+		return nil, err
+	} else if quote == '\'' {
+		return gocql.NewNativeType(p.proto, gocql.TypeCustom, name), nil
+	}
+
+	if quote == 0 {
+		if typ, ok := nativeTypes[name]; ok {
+			return gocql.NewNativeType(p.proto, typ, ""), nil
+		}
+
+		var typ gocql.Type
+		n := 1
+		switch name {
+		case "frozen":
+		case "list":
+			typ = gocql.TypeList
+		case "set":
+			typ = gocql.TypeSet
+		case "map":
+			typ, n = gocql.TypeMap, 2
+		case "tuple":
+			typ, n = gocql.TypeTuple, -1
+		default:
+			return p.udt(name)
+		}
+
+		params, err := p.params()
+		if err != nil {
+			return nil, err
+		} else if n > 0 && len(params) != n {
+			return nil, fmt.Errorf("%s expects %d types got %d", name, n, len(params))
+		}
+
+		switch typ {
+		case gocql.TypeList, gocql.TypeSet:
+			return gocql.CollectionType{NativeType: gocql.NewNativeType(p.proto, typ, ""), Elem: params[0]}, nil
+		case gocql.TypeMap:
+			return gocql.CollectionType{NativeType: gocql.NewNativeType(p.proto, typ, ""), Key: params[0], Elem: params[1]}, nil
+		case gocql.TypeTuple:
+			return gocql.TupleTypeInfo{NativeType: gocql.NewNativeType(p.proto, typ, ""), Elems: params}, nil
+		}
+		// frozen makes no difference to how values are written
+		return params[0], nil
+	}
+
+	return p.udt(name)
+}
+
+// udt returns the user defined type called name.
+func (p *typeParser) udt(name string) (gocql.TypeInfo, error) {
+	t := p.ks.Type(name)
+	if t == nil {
+		return nil, fmt.Errorf("unknown type %s", QuoteIdentifier(name))
+	}
+
+	fields := make([]gocql.UDTField, len(t.FieldNames))
+	for i, field := range t.FieldNames {
+		info, err := p.ks.TypeInfo(t.FieldTypes[i], p.proto)
+		if err != nil {
+			return nil, err
+		}
+		fields[i] = gocql.UDTField{Name: field, Type: info}
+	}
+
+	return gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(p.proto, gocql.TypeUDT, ""),
+		KeySpace:   p.ks.Name,
+		Name:       t.Name,
+		Elements:   fields,
+	}, nil
+}
@@ -0,0 +1,69 @@
+package metadata
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gocql/gocql"
+)
+
+func TestTypeInfo(t *testing.T) {
+	ks := &Keyspace{
+		Name: "shop",
+		Types: []*Type{
+			{Keyspace: "shop", Name: "address", FieldNames: []string{"street", "tags"}, FieldTypes: []string{"text", "set<text>"}},
+			{Keyspace: "shop", Name: "Contact", FieldNames: []string{"home"}, FieldTypes: []string{"frozen<address>"}},
+		},
+	}
+
+	native := func(typ gocql.Type) gocql.NativeType { return gocql.NewNativeType(4, typ, "") }
+	text := native(gocql.TypeText)
+	address := gocql.UDTTypeInfo{
+		NativeType: native(gocql.TypeUDT),
+		KeySpace:   "shop",
+		Name:       "address",
+		Elements: []gocql.UDTField{
+			{Name: "street", Type: text},
+			{Name: "tags", Type: gocql.CollectionType{NativeType: native(gocql.TypeSet), Elem: text}},
+		},
+	}
+
+	tests := [...]struct {
+		typ string
+		exp gocql.TypeInfo
+	}{
+		{"int", native(gocql.TypeInt)},
+		{"frozen<list<timeuuid>>", gocql.CollectionType{NativeType: native(gocql.TypeList), Elem: native(gocql.TypeTimeUUID)}},
+		{"map<text, frozen<tuple<int, blob>>>", gocql.CollectionType{
+			NativeType: native(gocql.TypeMap),
+			Key:        text,
+			Elem: gocql.TupleTypeInfo{
+				NativeType: native(gocql.TypeTuple),
+				Elems:      []gocql.TypeInfo{native(gocql.TypeInt), native(gocql.TypeBlob)},
+			},
+		}},
+		{"frozen<address>", address},
+		{`frozen<"Contact">`, gocql.UDTTypeInfo{
+			NativeType: native(gocql.TypeUDT),
+			KeySpace:   "shop",
+			Name:       "Contact",
+			Elements:   []gocql.UDTField{{Name: "home", Type: address}},
+		}},
+		{"'com.example.Custom'", gocql.NewNativeType(4, gocql.TypeCustom, "com.example.Custom")},
+	}
+
+	for _, test := range tests {
+		info, err := ks.TypeInfo(test.typ, 4)
+		if err != nil {
+			t.Errorf("%s: %v", test.typ, err)
+		} else if !reflect.DeepEqual(test.exp, info) {
+			t.Errorf("%s: expected %v got %v", test.typ, test.exp, info)
+		}
+	}
+
+	for _, typ := range []string{"", "list<int", "map<int>", "contact", "int>", "list<>"} {
+		if info, err := ks.TypeInfo(typ, 4); err == nil {
+			t.Errorf("%q: expected error got %v", typ, info)
+		}
+	}
+}
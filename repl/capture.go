@@ -0,0 +1,134 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gocql/gocqlsh/config"
+	"github.com/gocql/gocqlsh/cql/lexer"
+)
+
+// ansiEscape matches the escape sequences used to color output and
+// partialEscape the start of one at the end of a write.
+var (
+	ansiEscape    = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+	partialEscape = regexp.MustCompile(`\x1b(\[[0-9;?]*)?$`)
+)
+
+// capture is a file which output is appended to as well as being displayed,
+// escape sequences are removed so that the file is plain text. If writing to
+// the file fails capturing stops and err is set rather than failing the
+// output being displayed.
+type capture struct {
+	path string
+	f    *os.File
+	err  error
+	// partial is the start of an escape sequence which has not been
+	// completed by the last write
+	partial []byte
+}
+
+func openCapture(path string) (*capture, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &capture{path: path, f: f}, nil
+}
+
+func (cp *capture) Write(p []byte) (int, error) {
+	if cp.err != nil {
+		return len(p), nil
+	}
+
+	// an escape sequence may be split between writes, the start of it is
+	// held back until the rest is written
+	data := append(cp.partial, p...)
+	cp.partial = nil
+	if loc := partialEscape.FindIndex(data); loc != nil {
+		data, cp.partial = data[:loc[0]], data[loc[0]:]
+	}
+
+	_, cp.err = cp.f.Write(ansiEscape.ReplaceAll(data, nil))
+	return len(p), nil
+}
+
+func (cp *capture) close() error {
+	if len(cp.partial) > 0 && cp.err == nil {
+		_, cp.err = cp.f.Write(cp.partial)
+	}
+
+	err := cp.f.Close()
+	if cp.err != nil {
+		return fmt.Errorf("capturing output to %s failed: %v", cp.path, cp.err)
+	}
+	return err
+}
+
+// setOutput sets where output is displayed, it is also written to the
+// capture file if there is one.
+func (c *CQL) setOutput(out, errOut io.Writer) {
+	c.stdout, c.stderr = out, errOut
+	c.out, c.errOut = out, errOut
+	if c.capture != nil {
+		c.out = io.MultiWriter(out, c.capture)
+		c.errOut = io.MultiWriter(errOut, c.capture)
+	}
+}
+
+func (c *CQL) captureCommand(_ string, args *lexer.Lexer) error {
+	arg := args.ItemNoWS()
+	switch {
+	case arg.Typ == lexer.ItemEOF || arg.Typ == lexer.ItemSemiColon:
+		if c.capture == nil {
+			_, err := fmt.Fprintln(c.out, "Currently not capturing query output.")
+			return err
+		} else if c.capture.err != nil {
+			return fmt.Errorf("capturing output to %s failed: %v", c.capture.path, c.capture.err)
+		}
+		_, err := fmt.Fprintf(c.out, "Currently capturing query output to %s.\n", c.capture.path)
+		return err
+	case strings.EqualFold(arg.Val, "off"):
+		if c.capture == nil {
+			_, err := fmt.Fprintln(c.out, "Not currently capturing query output.")
+			return err
+		}
+
+		cp := c.capture
+		c.capture = nil
+		c.setOutput(c.stdout, c.stderr)
+		if err := cp.close(); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(c.out, "Stopped capturing query output to %s.\n", cp.path)
+		return err
+	case arg.Typ == lexer.ItemString:
+		cp, err := openCapture(config.ExpandHome(unquote(arg)))
+		if err != nil {
+			return err
+		}
+
+		if c.capture != nil {
+			if err := c.capture.close(); err != nil {
+				c.err(err)
+			}
+		}
+
+		// the confirmation is not captured
+		c.capture = nil
+		c.setOutput(c.stdout, c.stderr)
+		if _, err := fmt.Fprintf(c.out, "Now capturing query output to %s.\n", cp.path); err != nil {
+			cp.close()
+			return err
+		}
+
+		c.capture = cp
+		c.setOutput(c.stdout, c.stderr)
+		return nil
+	}
+
+	return fmt.Errorf("usage: CAPTURE ['<file>' | OFF]")
+}
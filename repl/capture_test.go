@@ -0,0 +1,79 @@
+package repl
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/logrusorgru/aurora"
+)
+
+func TestCaptureCommand(t *testing.T) {
+	c, out := newTestCQL()
+	// colors are not written to the capture file
+	c.au = aurora.NewAurora(true)
+	path := filepath.Join(t.TempDir(), "capture.txt")
+
+	steps := [...]struct {
+		stmt   string
+		output string
+	}{
+		{"CAPTURE", "Currently not capturing query output.\n"},
+		{"CAPTURE '" + path + "'", "Now capturing query output to " + path + ".\n"},
+		{"CAPTURE", "Currently capturing query output to " + path + ".\n"},
+		{"CAPTURE OFF", "Stopped capturing query output to " + path + ".\n"},
+		{"CAPTURE off", "Not currently capturing query output.\n"},
+	}
+
+	for i, step := range steps {
+		out.Reset()
+		if err := c.exec(step.stmt); err != nil {
+			t.Fatalf("%s: %v", step.stmt, err)
+		}
+		if out.String() != step.output {
+			t.Fatalf("%s: expected output %q got %q", step.stmt, step.output, out.String())
+		}
+
+		// output while capturing is written to both the display and the file
+		if i == 2 {
+			c.err(errors.New("boom"))
+		}
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "Currently capturing query output to " + path + ".\nerror: boom\n"
+	if string(b) != exp {
+		t.Fatalf("expected capture file %q got %q", exp, b)
+	}
+
+	if err := c.exec("CAPTURE maybe"); err == nil {
+		t.Fatal("expected error for invalid argument")
+	}
+}
+
+func TestCaptureSplitEscape(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.txt")
+	cp, err := openCapture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{"\x1b[3", "1mred\x1b", "[0m plain \x1b[1", "m"} {
+		cp.Write([]byte(p))
+	}
+	if err := cp.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := "red plain "; string(b) != exp {
+		t.Fatalf("expected %q got %q", exp, b)
+	}
+}
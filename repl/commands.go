@@ -0,0 +1,259 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gocql/gocqlsh/cql/lexer"
+)
+
+// errExit is returned by a command to stop the shell.
+var errExit = errors.New("exit")
+
+// commandFunc handles a shell command, stmt is the complete statement and
+// args is positioned after the command name.
+type commandFunc func(stmt string, args *lexer.Lexer) error
+
+type command struct {
+	// name is the canonical name of the command used in HELP
+	name  string
+	usage string
+	help  string
+	run   commandFunc
+}
+
+// dispatcher routes statements whose first word names a registered command to
+// that command, any other statements are passed to fallback.
+type dispatcher struct {
+	commands map[string]*command
+	fallback func(stmt string) error
+}
+
+func newDispatcher(fallback func(stmt string) error) *dispatcher {
+	return &dispatcher{
+		commands: make(map[string]*command),
+		fallback: fallback,
+	}
+}
+
+// register adds cmd under its name and any aliases, names are matched case
+// insensitively.
+func (d *dispatcher) register(cmd *command, aliases ...string) {
+	for _, name := range append([]string{cmd.name}, aliases...) {
+		d.commands[strings.ToLower(name)] = cmd
+	}
+}
+
+func (d *dispatcher) lookup(name string) (*command, bool) {
+	cmd, ok := d.commands[strings.ToLower(name)]
+	return cmd, ok
+}
+
+// command returns the command stmt starts with, args is positioned after the
+// command name.
+func (d *dispatcher) command(stmt string) (cmd *command, args *lexer.Lexer, ok bool) {
+	l := lexer.Lex(stmt)
+	switch first := l.ItemNoWS(); first.Typ {
+	case lexer.ItemKeyword, lexer.ItemIdentifier:
+		cmd, ok = d.lookup(first.Val)
+	}
+
+	return cmd, l, ok
+}
+
+func (d *dispatcher) dispatch(stmt string) error {
+	if cmd, args, ok := d.command(stmt); ok {
+		return cmd.run(stmt, args)
+	}
+
+	return d.fallback(stmt)
+}
+
+// list returns each registered command once, ordered by name.
+func (d *dispatcher) list() []*command {
+	seen := make(map[*command]bool)
+	var cmds []*command
+	for _, cmd := range d.commands {
+		if !seen[cmd] {
+			seen[cmd] = true
+			cmds = append(cmds, cmd)
+		}
+	}
+
+	sort.Slice(cmds, func(i, j int) bool {
+		return cmds[i].name < cmds[j].name
+	})
+	return cmds
+}
+
+func (c *CQL) registerCommands() {
+	c.commands.register(&command{
+		name:  "HELP",
+		usage: "HELP [<command>]",
+		help:  "Show the available shell commands or the usage of a single command.",
+		run:   c.help,
+	})
+	c.commands.register(&command{
+		name:  "EXIT",
+		usage: "EXIT",
+		help:  "Exit the shell.",
+		run: func(string, *lexer.Lexer) error {
+			return errExit
+		},
+	}, "QUIT")
+	c.commands.register(&command{
+		name:  "CLEAR",
+		usage: "CLEAR",
+		help:  "Clear the terminal.",
+		run:   c.clear,
+	}, "CLS")
+	c.commands.register(&command{
+		name:  "SHOW",
+		usage: "SHOW VERSION | HOST | SESSION <uuid>",
+		help:  "Show the version of the connected cluster, the host connected to or a previous trace session.",
+		run:   c.show,
+	})
+	c.commands.register(&command{
+		name:  "USE",
+		usage: "USE <keyspace>",
+		help:  "Use a keyspace for statements which do not name one, the keyspace is shown in the prompt.",
+		run:   c.useCommand,
+	})
+	c.commands.register(&command{
+		name:  "DESCRIBE",
+		usage: describeUsage,
+		help:  "Show the CQL statements which create a keyspace, a table with its indexes and views or another schema object.",
+		run:   c.describe,
+	}, "DESC")
+	c.commands.register(&command{
+		name:  "REFRESH",
+		usage: "REFRESH SCHEMA",
+		help:  "Discard the cached schema used by DESCRIBE and completion and read it again from the cluster.",
+		run:   c.refreshCommand,
+	})
+	c.commands.register(&command{
+		name:  "CONSISTENCY",
+		usage: "CONSISTENCY [<level>]",
+		help:  "Show or set the consistency level used for statements.",
+		run:   c.consistencyCommand,
+	})
+	c.commands.register(&command{
+		name:  "SERIAL",
+		usage: "SERIAL CONSISTENCY [<level>]",
+		help:  "Show or set the serial consistency level used for conditional updates.",
+		run:   c.serialConsistencyCommand,
+	})
+	c.commands.register(&command{
+		name:  "PAGING",
+		usage: "PAGING [ON | OFF | <page size>]",
+		help:  "Show or set whether query results are shown a page at a time.",
+		run:   c.pagingCommand,
+	})
+	c.commands.register(&command{
+		name:  "EXPAND",
+		usage: "EXPAND [ON | OFF | AUTO]",
+		help:  "Show or set whether rows are shown vertically, AUTO does so when the table is wider than the terminal.",
+		run:   c.expandCommand,
+	})
+	c.commands.register(&command{
+		name:  "FORMAT",
+		usage: "FORMAT [table | json | ndjson | csv | tsv | markdown]",
+		help:  "Show or set the format query results are written in.",
+		run:   c.formatCommand,
+	})
+	c.commands.register(&command{
+		name:  "CAPTURE",
+		usage: "CAPTURE ['<file>' | OFF]",
+		help:  "Append query output, including errors and traces, to a file as well as displaying it.",
+		run:   c.captureCommand,
+	})
+	c.commands.register(&command{
+		name:  "TRACING",
+		usage: "TRACING [ON | OFF]",
+		help:  "Show or set whether statements are traced, traces are shown after each result.",
+		run:   c.tracingCommand,
+	})
+	c.commands.register(&command{
+		name:  "COPY",
+		usage: copyUsage,
+		help:  "Export a table to or load a table from CSV, the options are HEADER, DELIMITER, NULL, PAGESIZE, MAXBATCHSIZE, INGESTRATE, NUMPROCESSES and ERRFILE.",
+		run:   c.copyCommand,
+	})
+	c.commands.register(&command{
+		name:  "HISTORY",
+		usage: "HISTORY [<n>]",
+		help:  "List the last n statements executed in this session, how long they took and whether they succeeded.",
+		run:   c.historyCommand,
+	})
+	c.commands.register(&command{
+		name:  "LOGIN",
+		usage: "LOGIN <username> [<password>]",
+		help:  "Reconnect as a different user, prompting for the password if it is not given.",
+		run:   c.login,
+	})
+	c.commands.register(&command{
+		name:  "SOURCE",
+		usage: "SOURCE '<file>'",
+		help:  "Execute the statements in a file.",
+		run:   c.source,
+	})
+}
+
+func (c *CQL) help(_ string, args *lexer.Lexer) error {
+	if name := args.ItemNoWS(); name.Typ != lexer.ItemEOF && name.Typ != lexer.ItemSemiColon {
+		cmd, ok := c.commands.lookup(name.Val)
+		if !ok {
+			return fmt.Errorf("no help for %q", name.Val)
+		}
+
+		_, err := fmt.Fprintf(c.out, "%s\n\n    %s\n", c.au.Bold(cmd.usage), cmd.help)
+		return err
+	}
+
+	if _, err := fmt.Fprintln(c.out, "Shell commands, any other statements are sent to the server:"); err != nil {
+		return err
+	}
+
+	for _, cmd := range c.commands.list() {
+		if _, err := fmt.Fprintf(c.out, "  %-40s %s\n", cmd.usage, cmd.help); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *CQL) clear(string, *lexer.Lexer) error {
+	// move the cursor home and clear the screen
+	_, err := fmt.Fprint(c.out, "\033[H\033[2J")
+	return err
+}
+
+func (c *CQL) show(_ string, args *lexer.Lexer) error {
+	what := args.ItemNoWS()
+	switch strings.ToLower(what.Val) {
+	case "version":
+		info, err := c.meta.ClusterMeta()
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(c.out, "[gocqlsh | Cassandra %s | CQL Spec %s | Native Protocol %s]\n", info.Version,
+			info.CQLVersion, info.Protocol)
+		return err
+	case "host":
+		info, err := c.meta.ClusterMeta()
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(c.out, "Connected to %s at %v:%d\n", c.au.Magenta(info.Name), info.Address, c.cluster.Port)
+		return err
+	case "session":
+		return c.showSession(args)
+	}
+
+	return fmt.Errorf("usage: SHOW VERSION | HOST | SESSION <uuid>")
+}
@@ -0,0 +1,73 @@
+package repl
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/gocql/gocqlsh/config"
+	"github.com/gocql/gocqlsh/cql/lexer"
+)
+
+// newTestCQL returns a CQL without a session for testing shell commands which
+// do not need to talk to the server, output is written to the returned buffer.
+func newTestCQL() (*CQL, *bytes.Buffer) {
+	cfg := config.Default()
+	cfg.UI.Color = false
+
+	out := &bytes.Buffer{}
+	return NewExec(cfg, gocql.NewCluster(), nil, out, out), out
+}
+
+func TestDispatcher(t *testing.T) {
+	var called []string
+	d := newDispatcher(func(stmt string) error {
+		called = append(called, "server: "+stmt)
+		return nil
+	})
+
+	record := func(name string) commandFunc {
+		return func(stmt string, args *lexer.Lexer) error {
+			called = append(called, name+": "+args.ItemNoWS().Val)
+			return nil
+		}
+	}
+
+	d.register(&command{name: "DESCRIBE", run: record("describe")}, "DESC")
+	d.register(&command{name: "EXIT", run: record("exit")}, "QUIT")
+
+	stmts := []string{
+		"DESCRIBE keyspaces",
+		"desc tables",
+		"  -- comment\n quit",
+		"select * from t",
+		"describeme",
+		"'describe'",
+	}
+	for _, stmt := range stmts {
+		if err := d.dispatch(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp := []string{
+		"describe: keyspaces",
+		"describe: tables",
+		"exit: ",
+		"server: select * from t",
+		"server: describeme",
+		"server: 'describe'",
+	}
+	if !reflect.DeepEqual(exp, called) {
+		t.Fatalf("expected %q got %q", exp, called)
+	}
+
+	var names []string
+	for _, cmd := range d.list() {
+		names = append(names, cmd.name)
+	}
+	if exp := []string{"DESCRIBE", "EXIT"}; !reflect.DeepEqual(exp, names) {
+		t.Fatalf("expected commands %q got %q", exp, names)
+	}
+}
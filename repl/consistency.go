@@ -0,0 +1,58 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gocql/gocql"
+	"github.com/gocql/gocqlsh/cql/lexer"
+)
+
+// consistencyArg returns the level given to a CONSISTENCY command or an empty
+// string if none was given.
+func consistencyArg(args *lexer.Lexer) string {
+	switch level := args.ItemNoWS(); level.Typ {
+	case lexer.ItemEOF, lexer.ItemSemiColon:
+		return ""
+	default:
+		return strings.ToUpper(unquote(level))
+	}
+}
+
+func (c *CQL) consistencyCommand(_ string, args *lexer.Lexer) error {
+	level := consistencyArg(args)
+	if level == "" {
+		_, err := fmt.Fprintf(c.out, "Current consistency level is %s.\n", c.au.Bold(c.consistency))
+		return err
+	}
+
+	cons, err := gocql.ParseConsistencyWrapper(level)
+	if err != nil {
+		return fmt.Errorf("invalid consistency level %q", level)
+	}
+
+	c.consistency = cons
+	_, err = fmt.Fprintf(c.out, "Consistency level set to %s.\n", c.au.Bold(cons))
+	return err
+}
+
+func (c *CQL) serialConsistencyCommand(_ string, args *lexer.Lexer) error {
+	if word := args.ItemNoWS(); !strings.EqualFold(word.Val, "consistency") {
+		return fmt.Errorf("usage: SERIAL CONSISTENCY [SERIAL | LOCAL_SERIAL]")
+	}
+
+	level := consistencyArg(args)
+	if level == "" {
+		_, err := fmt.Fprintf(c.out, "Current serial consistency level is %s.\n", c.au.Bold(c.serialConsistency))
+		return err
+	}
+
+	var cons gocql.SerialConsistency
+	if err := cons.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid serial consistency level %q, expected SERIAL or LOCAL_SERIAL", level)
+	}
+
+	c.serialConsistency = cons
+	_, err := fmt.Fprintf(c.out, "Serial consistency level set to %s.\n", c.au.Bold(cons))
+	return err
+}
@@ -0,0 +1,66 @@
+package repl
+
+import (
+	"testing"
+
+	"github.com/gocql/gocql"
+)
+
+func TestConsistencyCommand(t *testing.T) {
+	c, out := newTestCQL()
+
+	tests := [...]struct {
+		stmt   string
+		output string
+		cons   gocql.Consistency
+	}{
+		{"CONSISTENCY", "Current consistency level is QUORUM.\n", gocql.Quorum},
+		{"consistency local_quorum", "Consistency level set to LOCAL_QUORUM.\n", gocql.LocalQuorum},
+		{"CONSISTENCY ONE;", "Consistency level set to ONE.\n", gocql.One},
+		{"CONSISTENCY ;", "Current consistency level is ONE.\n", gocql.One},
+	}
+
+	for _, test := range tests {
+		out.Reset()
+		if err := c.exec(test.stmt); err != nil {
+			t.Fatalf("%s: %v", test.stmt, err)
+		}
+
+		if out.String() != test.output {
+			t.Errorf("%s: expected output %q got %q", test.stmt, test.output, out.String())
+		} else if c.consistency != test.cons {
+			t.Errorf("%s: expected consistency %v got %v", test.stmt, test.cons, c.consistency)
+		}
+	}
+
+	if err := c.exec("CONSISTENCY MOST"); err == nil {
+		t.Fatal("expected error for invalid consistency level")
+	} else if c.consistency != gocql.One {
+		t.Fatalf("invalid level changed consistency to %v", c.consistency)
+	}
+}
+
+func TestSerialConsistencyCommand(t *testing.T) {
+	c, out := newTestCQL()
+
+	if err := c.exec("SERIAL CONSISTENCY"); err != nil {
+		t.Fatal(err)
+	} else if exp := "Current serial consistency level is SERIAL.\n"; out.String() != exp {
+		t.Fatalf("expected output %q got %q", exp, out.String())
+	}
+
+	if err := c.exec("serial consistency local_serial"); err != nil {
+		t.Fatal(err)
+	} else if c.serialConsistency != gocql.LocalSerial {
+		t.Fatalf("expected LOCAL_SERIAL got %v", c.serialConsistency)
+	}
+
+	for _, stmt := range []string{"SERIAL CONSISTENCY QUORUM", "SERIAL ONE"} {
+		if err := c.exec(stmt); err == nil {
+			t.Errorf("%s: expected error", stmt)
+		}
+	}
+	if c.serialConsistency != gocql.LocalSerial {
+		t.Fatalf("invalid level changed serial consistency to %v", c.serialConsistency)
+	}
+}
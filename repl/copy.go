@@ -0,0 +1,375 @@
+package repl
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gocql/gocql"
+	"github.com/gocql/gocqlsh/config"
+	"github.com/gocql/gocqlsh/cql/lexer"
+)
+
+const copyUsage = "COPY [<keyspace>.]<table> [(<column>, ...)] TO '<file>' | STDOUT | FROM '<file>' [WITH <option> = <value> [AND ...]]"
+
+// copyProgressInterval is how often progress is reported while copying.
+const copyProgressInterval = time.Second
+
+// copyStatement is a parsed COPY command.
+type copyStatement struct {
+	keyspace string
+	table    string
+	// columns is empty to copy every column
+	columns []string
+	// from is set for COPY FROM
+	from bool
+	// file is empty for STDOUT
+	file    string
+	options copyOptions
+}
+
+type copyOptions struct {
+	header    bool
+	delimiter rune
+	// null is written in place of null values and values equal to it are
+	// loaded as null
+	null string
+
+	// pageSize is the number of rows fetched at a time by COPY TO
+	pageSize int
+
+	// maxBatchSize is the number of rows of a partition COPY FROM inserts in
+	// each batch
+	maxBatchSize int
+	// ingestRate is the maximum number of rows inserted per second
+	ingestRate int
+	// numProcesses is the number of batches inserted concurrently
+	numProcesses int
+	// errFile is where rows which could not be loaded are written, with why
+	// in errFile.log, if it is empty the file is named after the table
+	errFile string
+}
+
+func defaultCopyOptions() copyOptions {
+	return copyOptions{
+		delimiter:    ',',
+		pageSize:     1000,
+		maxBatchSize: 20,
+		ingestRate:   100000,
+		numProcesses: 4,
+	}
+}
+
+func (o *copyOptions) set(name, value string) error {
+	var err error
+	switch strings.ToLower(name) {
+	case "header":
+		o.header, err = strconv.ParseBool(value)
+	case "delimiter":
+		if value == `\t` {
+			value = "\t"
+		}
+		if utf8.RuneCountInString(value) != 1 {
+			return fmt.Errorf("DELIMITER must be a single character, got %q", value)
+		}
+		o.delimiter, _ = utf8.DecodeRuneInString(value)
+	case "null":
+		o.null = value
+	case "pagesize":
+		o.pageSize, err = positiveInt(value)
+	case "maxbatchsize":
+		o.maxBatchSize, err = positiveInt(value)
+	case "ingestrate":
+		o.ingestRate, err = positiveInt(value)
+	case "numprocesses":
+		o.numProcesses, err = positiveInt(value)
+	case "errfile":
+		o.errFile = config.ExpandHome(value)
+	default:
+		return fmt.Errorf("unknown COPY option %s", strings.ToUpper(name))
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid value %q for %s: %v", value, strings.ToUpper(name), err)
+	}
+	return nil
+}
+
+func positiveInt(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err == nil && n <= 0 {
+		err = fmt.Errorf("must be positive")
+	}
+	return n, err
+}
+
+// identifier returns the name of an identifier item, unquoted identifiers are
+// case insensitive and are returned in lower case.
+func identifier(item lexer.Item) string {
+	if item.Typ == lexer.ItemIdentifier && strings.HasPrefix(item.Val, `"`) {
+		return unquote(item)
+	}
+	return strings.ToLower(item.Val)
+}
+
+func isIdentifier(item lexer.Item) bool {
+	return item.Typ == lexer.ItemIdentifier || item.Typ == lexer.ItemKeyword
+}
+
+// qualifiedName parses [<keyspace>.]<name>, returning the item after it.
+func qualifiedName(args *lexer.Lexer) (keyspace, name string, next lexer.Item, ok bool) {
+	item := args.ItemNoWS()
+	if !isIdentifier(item) {
+		return "", "", item, false
+	}
+	name = identifier(item)
+
+	next = args.ItemNoWS()
+	if next.Typ == lexer.ItemDot {
+		if item = args.ItemNoWS(); !isIdentifier(item) {
+			return "", "", item, false
+		}
+		keyspace, name = name, identifier(item)
+		next = args.ItemNoWS()
+	}
+
+	return keyspace, name, next, true
+}
+
+// parseCopy parses the arguments to a COPY command.
+func parseCopy(args *lexer.Lexer) (*copyStatement, error) {
+	stmt := &copyStatement{options: defaultCopyOptions()}
+	usage := errors.New("usage: " + copyUsage)
+
+	var item lexer.Item
+	var ok bool
+	if stmt.keyspace, stmt.table, item, ok = qualifiedName(args); !ok {
+		return nil, usage
+	}
+
+	if item.Typ == lexer.ItemBracket && item.Val == "(" {
+		for {
+			if item = args.ItemNoWS(); !isIdentifier(item) {
+				return nil, usage
+			}
+			stmt.columns = append(stmt.columns, identifier(item))
+
+			item = args.ItemNoWS()
+			if item.Typ == lexer.ItemBracket && item.Val == ")" {
+				break
+			} else if item.Typ != lexer.ItemComma {
+				return nil, usage
+			}
+		}
+		item = args.ItemNoWS()
+	}
+
+	switch {
+	case strings.EqualFold(item.Val, "to"):
+	case item.Typ == lexer.ItemKeyword && item.Val == "from":
+		stmt.from = true
+	default:
+		return nil, usage
+	}
+
+	switch item = args.ItemNoWS(); {
+	case item.Typ == lexer.ItemString:
+		stmt.file = config.ExpandHome(unquote(item))
+	case strings.EqualFold(item.Val, "stdout") && !stmt.from:
+	default:
+		return nil, usage
+	}
+
+	item = args.ItemNoWS()
+	if item.Typ == lexer.ItemKeyword && item.Val == "with" {
+		for {
+			name := args.ItemNoWS()
+			if !isIdentifier(name) || args.ItemNoWS().Typ != lexer.ItemEquals {
+				return nil, usage
+			}
+
+			value := args.ItemNoWS()
+			v := value.Val
+			if value.Typ == lexer.ItemString {
+				v = unquote(value)
+			}
+			if err := stmt.options.set(name.Val, v); err != nil {
+				return nil, err
+			}
+
+			if item = args.ItemNoWS(); !(item.Typ == lexer.ItemKeyword && item.Val == "and") {
+				break
+			}
+		}
+	}
+
+	if item.Typ != lexer.ItemEOF && item.Typ != lexer.ItemSemiColon {
+		return nil, usage
+	}
+
+	return stmt, nil
+}
+
+func (c *CQL) copyCommand(_ string, args *lexer.Lexer) error {
+	stmt, err := parseCopy(args)
+	if err != nil {
+		return err
+	}
+
+	if stmt.keyspace == "" {
+		stmt.keyspace = c.cluster.Keyspace
+		if stmt.keyspace == "" {
+			return fmt.Errorf("no keyspace given for table %s", stmt.table)
+		}
+	}
+
+	if stmt.from {
+		return c.copyFrom(stmt)
+	}
+	return c.copyTo(stmt)
+}
+
+// copyTo exports the rows of a table as CSV, values are formatted as they
+// are when displayed.
+func (c *CQL) copyTo(stmt *copyStatement) error {
+	columns := "*"
+	if len(stmt.columns) > 0 {
+		quoted := make([]string, len(stmt.columns))
+		for i, col := range stmt.columns {
+			quoted[i] = quoteIdentifier(col)
+		}
+		columns = strings.Join(quoted, ", ")
+	}
+
+	// messages are written to errOut when the rows are written to out so that
+	// they can be redirected
+	out, status := c.out, c.errOut
+	dest := "STDOUT"
+	var file *os.File
+	if stmt.file != "" {
+		var err error
+		if file, err = os.Create(stmt.file); err != nil {
+			return err
+		}
+		defer file.Close()
+		out, status, dest = file, c.out, stmt.file
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s.%s", columns, quoteIdentifier(stmt.keyspace), quoteIdentifier(stmt.table))
+	iter := c.db.Query(query).Consistency(c.consistency).PageSize(stmt.options.pageSize).Iter()
+
+	start := time.Now()
+	progress := newCopyProgress(status, start, file != nil && c.r != nil)
+	n, err := c.writeCSV(out, iter, stmt.options, progress.update)
+	if closeErr := iter.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if file != nil {
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+
+	progress.done()
+	elapsed := time.Since(start)
+	_, err = fmt.Fprintf(status, "%d rows exported to %s in %.3f seconds (%.0f rows/s).\n",
+		n, dest, elapsed.Seconds(), rate(n, elapsed))
+	return err
+}
+
+// writeCSV writes the rows from iter to w as CSV, calling progress with the
+// number of rows written after each row.
+func (c *CQL) writeCSV(w io.Writer, iter *gocql.Iter, opts copyOptions, progress func(int)) (int, error) {
+	columns := iter.Columns()
+	cw := csv.NewWriter(w)
+	cw.Comma = opts.delimiter
+
+	record := make([]string, len(columns))
+	if opts.header {
+		for i, col := range columns {
+			record[i] = col.Name
+		}
+		if err := cw.Write(record); err != nil {
+			return 0, err
+		}
+	}
+
+	n := 0
+	rows := newRowReader(columns)
+	for rows.next(iter) {
+		for i, col := range columns {
+			record[i] = opts.null
+			if !isNull(rows.row[i]) {
+				record[i] = c.format.value(col.TypeInfo, rows.row[i], false)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return n, err
+		}
+
+		n++
+		progress(n)
+	}
+	if rows.err != nil {
+		return n, rows.err
+	}
+
+	cw.Flush()
+	return n, cw.Error()
+}
+
+// copyProgress periodically reports the number of rows copied and the rate
+// they are being copied at.
+type copyProgress struct {
+	w       io.Writer
+	enabled bool
+	start   time.Time
+	last    time.Time
+	// rows is the number of rows at the last report
+	rows    int
+	written bool
+}
+
+func newCopyProgress(w io.Writer, start time.Time, enabled bool) *copyProgress {
+	return &copyProgress{w: w, enabled: enabled, start: start, last: start}
+}
+
+func (p *copyProgress) update(n int) {
+	if !p.enabled {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(p.last) < copyProgressInterval {
+		return
+	}
+
+	fmt.Fprintf(p.w, "\rProcessed: %d rows; Rate: %.0f rows/s; Avg. rate: %.0f rows/s",
+		n, rate(n-p.rows, now.Sub(p.last)), rate(n, now.Sub(p.start)))
+	p.last, p.rows, p.written = now, n, true
+}
+
+// done ends the progress line if one was written.
+func (p *copyProgress) done() {
+	if p.written {
+		fmt.Fprintln(p.w)
+	}
+}
+
+// rate returns n per second over d.
+func rate(n int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
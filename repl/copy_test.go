@@ -0,0 +1,88 @@
+package repl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gocql/gocqlsh/cql/lexer"
+)
+
+func TestParseCopy(t *testing.T) {
+	defaults := defaultCopyOptions()
+	withOptions := func(f func(o *copyOptions)) copyOptions {
+		o := defaults
+		f(&o)
+		return o
+	}
+
+	tests := [...]struct {
+		in  string
+		exp copyStatement
+	}{
+		{"users TO 'users.csv'", copyStatement{table: "users", file: "users.csv", options: defaults}},
+		{`ks."Users" (id, "Name", key) to STDOUT;`, copyStatement{
+			keyspace: "ks",
+			table:    "Users",
+			columns:  []string{"id", "Name", "key"},
+			options:  defaults,
+		}},
+		{"Ks.T TO '/tmp/t.csv' WITH HEADER=true AND delimiter = '|' AND NULL='-' AND pagesize=10", copyStatement{
+			keyspace: "ks",
+			table:    "t",
+			file:     "/tmp/t.csv",
+			options: withOptions(func(o *copyOptions) {
+				o.header = true
+				o.delimiter = '|'
+				o.null = "-"
+				o.pageSize = 10
+			}),
+		}},
+		{"t (a, b) FROM 'in.csv' WITH MAXBATCHSIZE=5 AND INGESTRATE=100 AND NUMPROCESSES=2 AND ERRFILE='bad.csv'", copyStatement{
+			table:   "t",
+			columns: []string{"a", "b"},
+			from:    true,
+			file:    "in.csv",
+			options: withOptions(func(o *copyOptions) {
+				o.maxBatchSize = 5
+				o.ingestRate = 100
+				o.numProcesses = 2
+				o.errFile = "bad.csv"
+			}),
+		}},
+		{`t TO 't.tsv' WITH DELIMITER='\t'`, copyStatement{table: "t", file: "t.tsv", options: withOptions(func(o *copyOptions) {
+			o.delimiter = '\t'
+		})}},
+	}
+
+	for _, test := range tests {
+		stmt, err := parseCopy(lexer.Lex(test.in))
+		if err != nil {
+			t.Errorf("%s: %v", test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(&test.exp, stmt) {
+			t.Errorf("%s: expected %+v got %+v", test.in, test.exp, *stmt)
+		}
+	}
+
+	for _, in := range []string{
+		"",
+		"t",
+		"t TO",
+		"t TO file.csv",
+		"t (a b) TO 'f'",
+		"t TO 'f' WITH",
+		"t TO 'f' WITH HEADER",
+		"t TO 'f' WITH HEADER=maybe",
+		"t TO 'f' WITH DELIMITER='ab'",
+		"t TO 'f' WITH PAGESIZE=0",
+		"t TO 'f' WITH COLOUR=red",
+		"t TO 'f' extra",
+		"t FROM STDOUT",
+		"t FROM 'f' WITH NUMPROCESSES=0",
+	} {
+		if _, err := parseCopy(lexer.Lex(in)); err == nil {
+			t.Errorf("%q: expected error", in)
+		}
+	}
+}
@@ -0,0 +1,480 @@
+package repl
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/gocql/gocqlsh/metadata"
+)
+
+// copyColumn is a column loaded by COPY FROM and the type its values are
+// parsed as.
+type copyColumn struct {
+	name         string
+	typ          gocql.TypeInfo
+	partitionKey bool
+}
+
+// copyRow is a row read from the file being loaded.
+type copyRow struct {
+	line int
+	// raw is the row as it was read, it is written as is if it is rejected
+	raw    string
+	record []string
+	values []interface{}
+}
+
+// copyChunkSize is the number of rows read before they are grouped into
+// batches by partition.
+const copyChunkSize = 1000
+
+// copyLoader loads rows from CSV, inserting them in batches of rows in the
+// same partition with insert. Rows which can not be parsed or inserted are
+// written to rejects.
+type copyLoader struct {
+	columns []copyColumn
+	opts    copyOptions
+	f       *formatter
+	// insert inserts rows of values in the order of columns
+	insert  func(rows [][]interface{}) error
+	rejects *rejectWriter
+
+	mu       sync.Mutex
+	imported int
+	progress func(n int)
+}
+
+// copyFrom loads rows from a CSV file into a table.
+func (c *CQL) copyFrom(stmt *copyStatement) error {
+	columns, err := c.copyColumns(stmt)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(stmt.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	names := make([]string, len(columns))
+	markers := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = quoteIdentifier(col.name)
+		markers[i] = "?"
+	}
+	query := fmt.Sprintf("INSERT INTO %s.%s (%s) VALUES (%s)", quoteIdentifier(stmt.keyspace),
+		quoteIdentifier(stmt.table), strings.Join(names, ", "), strings.Join(markers, ", "))
+
+	errFile := stmt.options.errFile
+	if errFile == "" {
+		errFile = fmt.Sprintf("import_%s_%s.err", stmt.keyspace, stmt.table)
+	}
+	rejects := newRejectFile(errFile)
+
+	start := time.Now()
+	progress := newCopyProgress(c.out, start, c.r != nil)
+	loader := &copyLoader{
+		columns:  columns,
+		opts:     stmt.options,
+		f:        c.format,
+		insert:   c.inserter(query),
+		rejects:  rejects,
+		progress: progress.update,
+	}
+
+	imported, rejected, err := loader.load(f)
+	if closeErr := rejects.close(); err == nil {
+		err = closeErr
+	}
+	progress.done()
+	if err != nil {
+		return err
+	}
+
+	elapsed := time.Since(start)
+	if _, err := fmt.Fprintf(c.out, "%d rows imported from %s in %.3f seconds (%.0f rows/s).\n",
+		imported, stmt.file, elapsed.Seconds(), rate(imported, elapsed)); err != nil {
+		return err
+	}
+
+	if rejected > 0 {
+		return fmt.Errorf("%d rows were rejected, they have been written to %s and why to %s", rejected, errFile,
+			errFile+rejectLogSuffix)
+	}
+	return nil
+}
+
+// copyColumns returns the columns to load from the schema of the table.
+func (c *CQL) copyColumns(stmt *copyStatement) ([]copyColumn, error) {
+	ks, err := c.meta.Keyspace(stmt.keyspace)
+	if err != nil {
+		return nil, err
+	}
+
+	table := ks.Table(stmt.table)
+	if table == nil {
+		return nil, fmt.Errorf("table %s.%s does not exist", quoteIdentifier(stmt.keyspace), quoteIdentifier(stmt.table))
+	}
+
+	names := stmt.columns
+	if len(names) == 0 {
+		for _, col := range table.Columns {
+			names = append(names, col.Name)
+		}
+	}
+
+	// the values are marshalled by the driver when they are inserted, the
+	// types are only used to parse them so the protocol version is only a
+	// default when it is negotiated
+	proto := byte(c.cluster.ProtoVersion)
+	if proto == 0 {
+		proto = 4
+	}
+
+	columns := make([]copyColumn, len(names))
+	for i, name := range names {
+		col := table.Column(name)
+		if col == nil {
+			return nil, fmt.Errorf("table %s has no column %s", quoteIdentifier(stmt.table), quoteIdentifier(name))
+		}
+
+		typ, err := ks.TypeInfo(col.Type, proto)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %v", quoteIdentifier(name), err)
+		}
+
+		switch typ.Type() {
+		case gocql.TypeCounter:
+			return nil, fmt.Errorf("column %s is a counter which can not be loaded with COPY FROM", quoteIdentifier(name))
+		case gocql.TypeCustom:
+			return nil, fmt.Errorf("column %s has type %s which can not be loaded with COPY FROM",
+				quoteIdentifier(name), col.Type)
+		}
+
+		columns[i] = copyColumn{name: name, typ: typ, partitionKey: col.Kind == metadata.PartitionKey}
+	}
+
+	return columns, nil
+}
+
+// inserter returns a function which inserts rows with query, multiple rows
+// are inserted in an unlogged batch so they should be in the same partition.
+func (c *CQL) inserter(query string) func(rows [][]interface{}) error {
+	return func(rows [][]interface{}) error {
+		if len(rows) == 1 {
+			return c.db.Query(query, rows[0]...).Consistency(c.consistency).Exec()
+		}
+
+		batch := c.db.NewBatch(gocql.UnloggedBatch)
+		batch.SetConsistency(c.consistency)
+		for _, row := range rows {
+			batch.Query(query, row...)
+		}
+		return c.db.ExecuteBatch(batch)
+	}
+}
+
+// load reads and inserts every row from r, returning the number of rows
+// imported and rejected. An error is only returned if r could not be read or
+// the rejected rows could not be written.
+func (l *copyLoader) load(r io.Reader) (imported, rejected int, err error) {
+	input := &recordingReader{r: r}
+	cr := csv.NewReader(input)
+	cr.Comma = l.opts.delimiter
+	cr.FieldsPerRecord = -1
+
+	batches := make(chan []copyRow)
+	var wg sync.WaitGroup
+	for i := 0; i < l.opts.numProcesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				l.insertBatch(batch)
+			}
+		}()
+	}
+
+	start := time.Now()
+	sent := 0
+	send := func(batch []copyRow) {
+		// hold back batches which would exceed the ingest rate
+		due := start.Add(time.Duration(float64(sent) / float64(l.opts.ingestRate) * float64(time.Second)))
+		if wait := time.Until(due); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		sent += len(batch)
+		batches <- batch
+	}
+
+	var chunk []copyRow
+	needHeader := l.opts.header
+	for {
+		record, readErr := cr.Read()
+		if readErr == io.EOF {
+			break
+		}
+		perr, malformed := readErr.(*csv.ParseError)
+		if readErr != nil && !malformed {
+			err = readErr
+			break
+		}
+		raw := input.take(cr.InputOffset())
+
+		if needHeader {
+			// the rejected rows are written after the same header so that
+			// they can be loaded again with the same options
+			needHeader = false
+			l.mu.Lock()
+			l.rejects.header = raw
+			l.mu.Unlock()
+			continue
+		}
+
+		if malformed {
+			l.reject(copyRow{line: perr.StartLine, raw: raw}, perr.Err)
+			continue
+		}
+
+		line, _ := cr.FieldPos(0)
+		row := copyRow{line: line, raw: raw, record: record}
+		if row.values, readErr = l.parse(record); readErr != nil {
+			l.reject(row, readErr)
+			continue
+		}
+
+		if chunk = append(chunk, row); len(chunk) >= copyChunkSize {
+			for _, batch := range l.batches(chunk) {
+				send(batch)
+			}
+			chunk = nil
+		}
+	}
+	if err == nil {
+		for _, batch := range l.batches(chunk) {
+			send(batch)
+		}
+	}
+
+	close(batches)
+	wg.Wait()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err == nil {
+		err = l.rejects.err
+	}
+	return l.imported, l.rejects.n, err
+}
+
+// parse parses the fields of record as the types of the columns.
+func (l *copyLoader) parse(record []string) ([]interface{}, error) {
+	if len(record) != len(l.columns) {
+		return nil, fmt.Errorf("expected %d columns got %d", len(l.columns), len(record))
+	}
+
+	values := make([]interface{}, len(record))
+	for i, field := range record {
+		if field == l.opts.null {
+			continue
+		}
+
+		v, err := l.f.parse(l.columns[i].typ, field)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %v", l.columns[i].name, err)
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+// batches groups rows by partition into batches of at most maxBatchSize rows,
+// in the order each partition was first read.
+func (l *copyLoader) batches(rows []copyRow) [][]copyRow {
+	var keys []string
+	partitions := map[string][]copyRow{}
+	for _, row := range rows {
+		var key []string
+		for i, col := range l.columns {
+			if col.partitionKey {
+				key = append(key, row.record[i])
+			}
+		}
+
+		k := fmt.Sprintf("%q", key)
+		if _, ok := partitions[k]; !ok {
+			keys = append(keys, k)
+		}
+		partitions[k] = append(partitions[k], row)
+	}
+
+	var batches [][]copyRow
+	for _, k := range keys {
+		for rows := partitions[k]; len(rows) > 0; {
+			n := l.opts.maxBatchSize
+			if n > len(rows) {
+				n = len(rows)
+			}
+			batches = append(batches, rows[:n])
+			rows = rows[n:]
+		}
+	}
+	return batches
+}
+
+// insertBatch inserts batch, if it fails each row is retried individually so
+// that only the rows which fail are rejected.
+func (l *copyLoader) insertBatch(batch []copyRow) {
+	values := make([][]interface{}, len(batch))
+	for i, row := range batch {
+		values[i] = row.values
+	}
+
+	err := l.insert(values)
+	if err == nil {
+		l.inserted(len(batch))
+		return
+	} else if len(batch) == 1 {
+		l.reject(batch[0], err)
+		return
+	}
+
+	for _, row := range batch {
+		if err := l.insert([][]interface{}{row.values}); err != nil {
+			l.reject(row, err)
+		} else {
+			l.inserted(1)
+		}
+	}
+}
+
+func (l *copyLoader) inserted(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.imported += n
+	if l.progress != nil {
+		l.progress(l.imported)
+	}
+}
+
+func (l *copyLoader) reject(row copyRow, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rejects.write(row, err)
+}
+
+// recordingReader keeps what has been read from r until it is taken, so that
+// the input of each CSV record is known.
+type recordingReader struct {
+	r io.Reader
+	// buf holds what has been read from offset on
+	buf    []byte
+	offset int64
+}
+
+func (rr *recordingReader) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	rr.buf = append(rr.buf, p[:n]...)
+	return n, err
+}
+
+// take returns what was read up to offset since it was last called.
+func (rr *recordingReader) take(offset int64) string {
+	n := offset - rr.offset
+	s := string(rr.buf[:n])
+	rr.buf = append(rr.buf[:0], rr.buf[n:]...)
+	rr.offset = offset
+	return s
+}
+
+// rejectLogSuffix is added to the name of the file rejected rows are written
+// to for the file why each was rejected is written to.
+const rejectLogSuffix = ".log"
+
+// rejectWriter writes rows which could not be loaded as they were read,
+// after header if it is set, so that they can be loaded again once they are
+// fixed. The line each row was read from and why it was rejected are written
+// to a log. Neither is opened until the first row is rejected.
+type rejectWriter struct {
+	open   func() (rows, log io.WriteCloser, err error)
+	header string
+
+	rows, log io.WriteCloser
+	// n is the number of rows rejected
+	n   int
+	err error
+}
+
+func newRejectFile(path string) *rejectWriter {
+	return &rejectWriter{
+		open: func() (io.WriteCloser, io.WriteCloser, error) {
+			rows, err := os.Create(path)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			log, err := os.Create(path + rejectLogSuffix)
+			if err != nil {
+				rows.Close()
+				return nil, nil, err
+			}
+			return rows, log, nil
+		},
+	}
+}
+
+func (r *rejectWriter) write(row copyRow, reason error) {
+	r.n++
+	if r.err != nil {
+		return
+	}
+
+	if r.rows == nil {
+		if r.rows, r.log, r.err = r.open(); r.err != nil {
+			return
+		}
+		if r.header != "" {
+			if r.err = r.writeRow(r.header); r.err != nil {
+				return
+			}
+		}
+	}
+
+	if _, r.err = fmt.Fprintf(r.log, "line %d: %v\n", row.line, reason); r.err == nil {
+		r.err = r.writeRow(row.raw)
+	}
+}
+
+// writeRow writes raw, ending it with a line break if the last line of the
+// input did not have one.
+func (r *rejectWriter) writeRow(raw string) error {
+	if !strings.HasSuffix(raw, "\n") {
+		raw += "\n"
+	}
+	_, err := io.WriteString(r.rows, raw)
+	return err
+}
+
+func (r *rejectWriter) close() error {
+	if r.rows == nil {
+		return r.err
+	}
+
+	for _, f := range []io.Closer{r.rows, r.log} {
+		if err := f.Close(); r.err == nil {
+			r.err = err
+		}
+	}
+	return r.err
+}
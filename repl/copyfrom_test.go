@@ -0,0 +1,226 @@
+package repl
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gocql/gocql"
+)
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+func TestCopyLoader(t *testing.T) {
+	c, _ := newTestCQL()
+
+	// fakeTable stands in for the session, rows with a negative id are
+	// refused as the server would refuse an invalid row.
+	var (
+		mu    sync.Mutex
+		table = map[int64][]interface{}{}
+	)
+	insert := func(rows [][]interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		for _, row := range rows {
+			if row[0].(int64) < 0 {
+				return errors.New("invalid id")
+			}
+		}
+		for _, row := range rows {
+			table[row[0].(int64)] = row
+		}
+		return nil
+	}
+
+	var rejected, reasons bytes.Buffer
+	open := func() (io.WriteCloser, io.WriteCloser, error) {
+		return nopCloser{&rejected}, nopCloser{&reasons}, nil
+	}
+	opts := defaultCopyOptions()
+	opts.header = true
+	opts.delimiter = '|'
+	opts.null = "-"
+	opts.maxBatchSize = 2
+	opts.numProcesses = 1
+
+	loader := &copyLoader{
+		columns: []copyColumn{
+			{"id", native(gocql.TypeInt), true},
+			{"name", native(gocql.TypeVarchar), false},
+			{"tags", collection(gocql.TypeSet, nil, native(gocql.TypeVarchar)), false},
+		},
+		opts:    opts,
+		f:       c.format,
+		insert:  insert,
+		rejects: &rejectWriter{open: open},
+	}
+
+	input := strings.Join([]string{
+		"id|name|tags",
+		"1|alice|{'a', 'b'}",
+		"2|-|-",
+		"three|carol|{}",
+		`7|gr"ace|{}`,
+		"-4|dave|{}",
+		"5|erin",
+		"6|frank|{'c'}",
+		"",
+	}, "\n")
+
+	imported, nrejected, err := loader.load(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.rejects.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if imported != 3 || nrejected != 4 {
+		t.Fatalf("expected 3 rows imported and 4 rejected got %d and %d", imported, nrejected)
+	}
+
+	exp := map[int64][]interface{}{
+		1: {int64(1), "alice", []interface{}{"a", "b"}},
+		2: {int64(2), nil, nil},
+		6: {int64(6), "frank", []interface{}{"c"}},
+	}
+	if !reflect.DeepEqual(exp, table) {
+		t.Fatalf("expected table %v got %v", exp, table)
+	}
+
+	// the rejected rows, including those which are not valid CSV, can be
+	// loaded again with the same options
+	expRejected := `id|name|tags
+three|carol|{}
+7|gr"ace|{}
+5|erin
+-4|dave|{}
+`
+	if rejected.String() != expRejected {
+		t.Fatalf("expected rejected rows:\n%s\ngot:\n%s", expRejected, rejected.String())
+	}
+
+	expReasons := `line 4: column id: strconv.ParseInt: parsing "three": invalid syntax
+line 5: bare " in non-quoted-field
+line 7: expected 3 columns got 2
+line 6: invalid id
+`
+	if reasons.String() != expReasons {
+		t.Fatalf("expected reasons:\n%s\ngot:\n%s", expReasons, reasons.String())
+	}
+}
+
+func TestCopyLoaderBatches(t *testing.T) {
+	c, _ := newTestCQL()
+
+	var (
+		mu      sync.Mutex
+		batches [][]int64
+	)
+	insert := func(rows [][]interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var batch []int64
+		for _, row := range rows {
+			batch = append(batch, row[0].(int64))
+		}
+		batches = append(batches, batch)
+		if batch[0] < 0 && len(batch) > 1 {
+			return errors.New("invalid batch")
+		}
+		return nil
+	}
+
+	opts := defaultCopyOptions()
+	opts.maxBatchSize = 2
+	opts.numProcesses = 1
+	loader := &copyLoader{
+		columns: []copyColumn{
+			{"pk", native(gocql.TypeInt), true},
+			{"ck", native(gocql.TypeInt), false},
+		},
+		opts:    opts,
+		f:       c.format,
+		insert:  insert,
+		rejects: &rejectWriter{},
+	}
+
+	imported, _, err := loader.load(strings.NewReader("1,1\n2,1\n1,2\n1,3\n-1,1\n-1,2\n"))
+	if err != nil {
+		t.Fatal(err)
+	} else if imported != 6 {
+		t.Fatalf("expected 6 rows imported got %d", imported)
+	}
+
+	// only rows in the same partition are batched, a batch which fails is
+	// retried a row at a time
+	exp := [][]int64{{1, 1}, {1}, {2}, {-1, -1}, {-1}, {-1}}
+	if !reflect.DeepEqual(exp, batches) {
+		t.Fatalf("expected batches %v got %v", exp, batches)
+	}
+}
+
+func TestCopyLoaderMalformedHeader(t *testing.T) {
+	c, _ := newTestCQL()
+
+	var loaded []interface{}
+	var rejected bytes.Buffer
+	opts := defaultCopyOptions()
+	opts.header = true
+	opts.numProcesses = 1
+	loader := &copyLoader{
+		columns: []copyColumn{{"id", native(gocql.TypeInt), true}},
+		opts:    opts,
+		f:       c.format,
+		insert: func(rows [][]interface{}) error {
+			for _, row := range rows {
+				loaded = append(loaded, row[0])
+			}
+			return nil
+		},
+		rejects: &rejectWriter{open: func() (io.WriteCloser, io.WriteCloser, error) {
+			return nopCloser{&rejected}, nopCloser{ioutil.Discard}, nil
+		}},
+	}
+
+	// the header is skipped even if it is not valid CSV
+	imported, _, err := loader.load(strings.NewReader("i\"d\n1\nx"))
+	if err != nil {
+		t.Fatal(err)
+	} else if err := loader.rejects.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if imported != 1 || !reflect.DeepEqual(loaded, []interface{}{int64(1)}) {
+		t.Fatalf("expected only the first row to be loaded got %v", loaded)
+	}
+	if exp := "i\"d\nx\n"; rejected.String() != exp {
+		t.Fatalf("expected rejected rows %q got %q", exp, rejected.String())
+	}
+}
+
+func TestRejectWriterUnused(t *testing.T) {
+	opened := false
+	r := &rejectWriter{open: func() (io.WriteCloser, io.WriteCloser, error) {
+		opened = true
+		return nopCloser{ioutil.Discard}, nopCloser{ioutil.Discard}, nil
+	}}
+
+	if err := r.close(); err != nil {
+		t.Fatal(err)
+	} else if opened {
+		t.Fatal("reject file should not be created when no rows are rejected")
+	}
+}
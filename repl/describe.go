@@ -0,0 +1,309 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/gocql/gocqlsh/cql/lexer"
+	"github.com/gocql/gocqlsh/metadata"
+)
+
+const describeUsage = "DESCRIBE CLUSTER | KEYSPACES | TABLES | [FULL] SCHEMA | KEYSPACE [<keyspace>] | TABLE | TYPE | FUNCTION | AGGREGATE | INDEX | MATERIALIZED VIEW [<keyspace>.]<name>"
+
+// describeStatement is a parsed DESCRIBE command.
+type describeStatement struct {
+	// kind is what is being described, ie keyspace, table or materialized view
+	kind     string
+	keyspace string
+	name     string
+}
+
+// parseDescribe parses the arguments to a DESCRIBE command.
+func parseDescribe(args *lexer.Lexer) (*describeStatement, error) {
+	usage := errors.New("usage: " + describeUsage)
+
+	item := args.ItemNoWS()
+	if !isIdentifier(item) {
+		return nil, usage
+	}
+
+	stmt := &describeStatement{kind: strings.ToLower(item.Val)}
+	switch stmt.kind {
+	case "cluster", "keyspaces", "tables", "schema":
+		item = args.ItemNoWS()
+	case "full":
+		if schema := args.ItemNoWS(); !strings.EqualFold(schema.Val, "schema") {
+			return nil, usage
+		}
+		stmt.kind = "schema"
+		item = args.ItemNoWS()
+	case "keyspace":
+		item = args.ItemNoWS()
+		if isIdentifier(item) {
+			stmt.keyspace = identifier(item)
+			item = args.ItemNoWS()
+		}
+	case "columnfamily":
+		stmt.kind = "table"
+		fallthrough
+	case "table", "type", "function", "aggregate", "index", "materialized":
+		if stmt.kind == "materialized" {
+			if view := args.ItemNoWS(); !strings.EqualFold(view.Val, "view") {
+				return nil, usage
+			}
+			stmt.kind = "materialized view"
+		}
+
+		var ok bool
+		if stmt.keyspace, stmt.name, item, ok = qualifiedName(args); !ok {
+			return nil, usage
+		}
+	default:
+		return nil, usage
+	}
+
+	if item.Typ != lexer.ItemEOF && item.Typ != lexer.ItemSemiColon {
+		return nil, usage
+	}
+
+	return stmt, nil
+}
+
+// ddl returns the statements which create what stmt describes in ks.
+func (stmt *describeStatement) ddl(ks *metadata.Keyspace) (string, error) {
+	var stmts []string
+	switch stmt.kind {
+	case "keyspace":
+		return ks.DDL(), nil
+	case "table":
+		if t := ks.Table(stmt.name); t != nil {
+			return t.DDL(), nil
+		}
+	case "materialized view":
+		if v := ks.View(stmt.name); v != nil {
+			return v.CQL(), nil
+		}
+	case "type":
+		if t := ks.Type(stmt.name); t != nil {
+			return t.CQL(), nil
+		}
+	case "index":
+		if idx := ks.Index(stmt.name); idx != nil {
+			return idx.CQL(), nil
+		}
+	case "function":
+		for _, fn := range ks.FunctionsNamed(stmt.name) {
+			stmts = append(stmts, fn.CQL())
+		}
+	case "aggregate":
+		for _, agg := range ks.AggregatesNamed(stmt.name) {
+			stmts = append(stmts, agg.CQL())
+		}
+	}
+
+	if len(stmts) == 0 {
+		return "", fmt.Errorf("%s %s.%s not found", stmt.kind, quoteIdentifier(ks.Name), quoteIdentifier(stmt.name))
+	}
+	return strings.Join(stmts, "\n\n"), nil
+}
+
+func (c *CQL) describe(_ string, args *lexer.Lexer) error {
+	stmt, err := parseDescribe(args)
+	if err != nil {
+		return err
+	}
+
+	switch stmt.kind {
+	case "cluster":
+		return c.describeCluster()
+	case "keyspaces":
+		return c.describeKeyspaces()
+	case "tables":
+		return c.describeTables()
+	case "schema":
+		ddl, err := c.schemaDDL()
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(c.out, "\n%s\n\n", ddl)
+		return err
+	}
+
+	if stmt.keyspace == "" {
+		if stmt.keyspace = c.keyspace(); stmt.keyspace == "" {
+			return fmt.Errorf("no keyspace given and no keyspace is in use")
+		}
+	}
+
+	ks, err := c.meta.Keyspace(stmt.keyspace)
+	if err != nil {
+		return err
+	}
+
+	ddl, err := stmt.ddl(ks)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.out, "\n%s\n\n", ddl)
+	return err
+}
+
+// schemaDDL returns the statements which create every keyspace other than
+// the system keyspaces.
+func (c *CQL) schemaDDL() (string, error) {
+	keyspaces, err := c.meta.Keyspaces()
+	if err != nil {
+		return "", err
+	}
+	return metadata.SchemaDDL(keyspaces), nil
+}
+
+// DumpSchema writes the statements which create every keyspace other than
+// the system keyspaces to the file at path, executing the file with SOURCE
+// recreates the schema.
+func (c *CQL) DumpSchema(path string) error {
+	ddl, err := c.schemaDDL()
+	if err == nil {
+		err = ioutil.WriteFile(path, []byte(ddl+"\n"), 0644)
+	}
+
+	if err != nil {
+		c.err(err)
+	}
+	return err
+}
+
+// describeCluster shows the cluster name and partitioner, and which hosts
+// own each token for the current keyspace.
+func (c *CQL) describeCluster() error {
+	info, err := c.meta.ClusterMeta()
+	if err != nil {
+		return err
+	}
+
+	partitioner := info.Partitioner[strings.LastIndex(info.Partitioner, ".")+1:]
+	if _, err := fmt.Fprintf(c.out, "\nCluster: %s\nPartitioner: %s\n", info.Name, partitioner); err != nil {
+		return err
+	}
+
+	if c.keyspace() == "" {
+		_, err := fmt.Fprintln(c.out)
+		return err
+	}
+
+	ks, err := c.meta.Keyspace(c.keyspace())
+	if err != nil {
+		return err
+	}
+
+	hosts, err := c.meta.Hosts()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.out, "\nRange ownership for %s:\n%s\n", quoteIdentifier(ks.Name),
+		formatOwnership(metadata.Ownership(hosts, ks.Replication)))
+	return err
+}
+
+// formatOwnership lists each token with its replicas.
+func formatOwnership(owners []metadata.TokenOwner) string {
+	var b strings.Builder
+	for _, owner := range owners {
+		replicas := make([]string, len(owner.Replicas))
+		for i, h := range owner.Replicas {
+			replicas[i] = h.Address.String()
+		}
+		fmt.Fprintf(&b, " %39s  [%s]\n", owner.Token, strings.Join(replicas, ", "))
+	}
+	return b.String()
+}
+
+// describeKeyspaces lists the name of every keyspace.
+func (c *CQL) describeKeyspaces() error {
+	keyspaces, err := c.meta.Keyspaces()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(keyspaces))
+	for i, ks := range keyspaces {
+		names[i] = quoteIdentifier(ks.Name)
+	}
+
+	_, err = fmt.Fprintf(c.out, "\n%s\n\n", columnize(names, c.width()))
+	return err
+}
+
+// describeTables lists the tables in the current keyspace or, if there is
+// none, in every keyspace grouped by keyspace.
+func (c *CQL) describeTables() error {
+	var keyspaces []*metadata.Keyspace
+	if c.keyspace() != "" {
+		ks, err := c.meta.Keyspace(c.keyspace())
+		if err != nil {
+			return err
+		}
+		keyspaces = append(keyspaces, ks)
+	} else {
+		var err error
+		if keyspaces, err = c.meta.Keyspaces(); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprint(c.out, formatTables(keyspaces, c.keyspace() == "", c.width()))
+	return err
+}
+
+// formatTables lists the tables in each keyspace, headed by the name of the
+// keyspace if grouped is set.
+func formatTables(keyspaces []*metadata.Keyspace, grouped bool, width int) string {
+	var b strings.Builder
+	for _, ks := range keyspaces {
+		b.WriteString("\n")
+		if grouped {
+			heading := "Keyspace " + quoteIdentifier(ks.Name)
+			fmt.Fprintf(&b, "%s\n%s\n", heading, strings.Repeat("-", len(heading)))
+		}
+
+		names := make([]string, len(ks.Tables))
+		for i, t := range ks.Tables {
+			names[i] = quoteIdentifier(t.Name)
+		}
+		if len(names) == 0 {
+			b.WriteString("<empty>\n")
+		} else {
+			fmt.Fprintf(&b, "%s\n", columnize(names, width))
+		}
+	}
+
+	b.WriteString("\n")
+	return b.String()
+}
+
+// columnize joins names with two spaces, wrapping lines which would be
+// wider than width if it is set.
+func columnize(names []string, width int) string {
+	var b strings.Builder
+	line := 0
+	for i, name := range names {
+		if i > 0 {
+			if width > 0 && line+2+len(name) > width {
+				b.WriteString("\n")
+				line = 0
+			} else {
+				b.WriteString("  ")
+				line += 2
+			}
+		}
+
+		b.WriteString(name)
+		line += len(name)
+	}
+	return b.String()
+}
@@ -0,0 +1,124 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gocql/gocqlsh/cql/lexer"
+	"github.com/gocql/gocqlsh/metadata"
+)
+
+func TestParseDescribe(t *testing.T) {
+	tests := [...]struct {
+		args string
+		exp  describeStatement
+	}{
+		{"CLUSTER", describeStatement{kind: "cluster"}},
+		{"SCHEMA", describeStatement{kind: "schema"}},
+		{"full schema;", describeStatement{kind: "schema"}},
+		{"keyspaces;", describeStatement{kind: "keyspaces"}},
+		{"KEYSPACE", describeStatement{kind: "keyspace"}},
+		{"keyspace Shop;", describeStatement{kind: "keyspace", keyspace: "shop"}},
+		{"TABLE orders", describeStatement{kind: "table", name: "orders"}},
+		{"COLUMNFAMILY shop.orders", describeStatement{kind: "table", keyspace: "shop", name: "orders"}},
+		{`TYPE "Shop".address`, describeStatement{kind: "type", keyspace: "Shop", name: "address"}},
+		{"FUNCTION add", describeStatement{kind: "function", name: "add"}},
+		{"AGGREGATE shop.sum_total", describeStatement{kind: "aggregate", keyspace: "shop", name: "sum_total"}},
+		{"INDEX orders_items", describeStatement{kind: "index", name: "orders_items"}},
+		{"MATERIALIZED VIEW shop.orders_by_total;", describeStatement{kind: "materialized view", keyspace: "shop", name: "orders_by_total"}},
+	}
+
+	for _, test := range tests {
+		stmt, err := parseDescribe(lexer.Lex(test.args))
+		if err != nil {
+			t.Errorf("%s: %v", test.args, err)
+		} else if *stmt != test.exp {
+			t.Errorf("%s: expected %+v got %+v", test.args, test.exp, *stmt)
+		}
+	}
+
+	for _, args := range []string{"", "TABLE", "MATERIALIZED orders", "TABLE a.b.c", "KEYSPACE a b", "TABLES shop", "FULL TABLES", "TRIGGER t"} {
+		if _, err := parseDescribe(lexer.Lex(args)); err == nil {
+			t.Errorf("%q: expected usage error", args)
+		}
+	}
+}
+
+func TestDescribeDDL(t *testing.T) {
+	ks := &metadata.Keyspace{
+		Name: "shop",
+		Functions: []*metadata.Function{
+			{Keyspace: "shop", Name: "add", ArgumentNames: []string{"a"}, ArgumentTypes: []string{"int"},
+				ReturnType: "int", Language: "java", Body: "return a;"},
+			{Keyspace: "shop", Name: "add", ArgumentNames: []string{"a"}, ArgumentTypes: []string{"text"},
+				ReturnType: "text", Language: "java", Body: "return a;", CalledOnNullInput: true},
+		},
+	}
+
+	ddl, err := (&describeStatement{kind: "function", name: "add"}).ddl(ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := ks.Functions[0].CQL() + "\n\n" + ks.Functions[1].CQL()
+	if ddl != exp {
+		t.Errorf("expected every overload:\n%s\ngot:\n%s", exp, ddl)
+	}
+
+	_, err = (&describeStatement{kind: "table", name: "Orders"}).ddl(ks)
+	if err == nil || err.Error() != `table shop."Orders" not found` {
+		t.Errorf("expected not found error got %v", err)
+	}
+}
+
+func TestFormatTables(t *testing.T) {
+	keyspaces := []*metadata.Keyspace{
+		{Name: "empty"},
+		{Name: "Shop", Tables: []*metadata.Table{{Name: "customers"}, {Name: "orders"}, {Name: "Items"}}},
+	}
+
+	exp := `
+Keyspace empty
+--------------
+<empty>
+
+Keyspace "Shop"
+---------------
+customers  orders
+"Items"
+
+`
+	if got := formatTables(keyspaces, true, 20); got != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, got)
+	}
+
+	exp = "\ncustomers  orders  \"Items\"\n\n"
+	if got := formatTables(keyspaces[1:], false, 0); got != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, got)
+	}
+}
+
+func TestSchemaDDLStatements(t *testing.T) {
+	ks := &metadata.Keyspace{
+		Name:        "shop",
+		Replication: map[string]string{"class": "SimpleStrategy", "replication_factor": "1"},
+		Functions: []*metadata.Function{
+			{Keyspace: "shop", Name: "twice", ArgumentNames: []string{"a"}, ArgumentTypes: []string{"int"},
+				ReturnType: "int", Language: "java", Body: "int b = a * 2; return b;"},
+		},
+		Aggregates: []*metadata.Aggregate{
+			{Keyspace: "shop", Name: "total", ArgumentTypes: []string{"int"}, StateFunc: "twice", StateType: "int",
+				InitCond: "0"},
+		},
+	}
+
+	// statements containing semicolons in function bodies must be read back
+	// whole by SOURCE
+	stmts := splitStatements(metadata.SchemaDDL([]*metadata.Keyspace{ks}))
+	if len(stmts) != 3 {
+		t.Fatalf("expected 3 statements got %d: %v", len(stmts), stmts)
+	}
+	if stmt := stmts[1].cql; !strings.HasSuffix(stmt, "AS $$int b = a * 2; return b;$$") {
+		t.Errorf("expected function body to be kept whole got %q", stmt)
+	}
+}
@@ -0,0 +1,130 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gocql/gocqlsh/cql/lexer"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// expandMode controls whether results are shown as a table or vertically with
+// a block per row.
+type expandMode int
+
+const (
+	// expandAuto shows rows vertically when the table would be wider than
+	// the terminal.
+	expandAuto expandMode = iota
+	expandOff
+	expandOn
+)
+
+func (m expandMode) String() string {
+	switch m {
+	case expandOff:
+		return "disabled"
+	case expandOn:
+		return "enabled"
+	default:
+		return "automatic"
+	}
+}
+
+func (c *CQL) expandCommand(_ string, args *lexer.Lexer) error {
+	arg := args.ItemNoWS()
+	switch {
+	case arg.Typ == lexer.ItemEOF || arg.Typ == lexer.ItemSemiColon:
+		_, err := fmt.Fprintf(c.out, "Expanded output is currently %s.\n", c.expand)
+		return err
+	case arg.Typ == lexer.ItemKeyword && arg.Val == "on":
+		c.expand = expandOn
+	case strings.EqualFold(arg.Val, "off"):
+		c.expand = expandOff
+	case strings.EqualFold(arg.Val, "auto"):
+		c.expand = expandAuto
+	default:
+		return fmt.Errorf("usage: EXPAND [ON | OFF | AUTO]")
+	}
+
+	_, err := fmt.Fprintf(c.out, "Expanded output is now %s.\n", c.expand)
+	return err
+}
+
+// width returns the width of the terminal or 0 if it is not known.
+func (c *CQL) width() int {
+	if c.r == nil || c.r.Config.FuncGetWidth == nil {
+		return 0
+	}
+
+	if w := c.r.Config.FuncGetWidth(); w > 0 {
+		return w
+	}
+	return 0
+}
+
+// expanded reports whether rows should be rendered vertically.
+func (c *CQL) expanded(header []string, rows [][]string) bool {
+	switch c.expand {
+	case expandOn:
+		return true
+	case expandOff:
+		return false
+	}
+
+	width := c.width()
+	return width > 0 && tableWidth(header, rows) > width
+}
+
+// tableWidth returns the width of the table tablewriter renders for header and
+// rows, each column is padded by a space either side and separated by a |.
+func tableWidth(header []string, rows [][]string) int {
+	width := 1
+	for i := range header {
+		col := tablewriter.DisplayWidth(header[i])
+		for _, row := range rows {
+			if w := tablewriter.DisplayWidth(row[i]); w > col {
+				col = w
+			}
+		}
+		width += col + 3
+	}
+
+	return width
+}
+
+// renderExpanded writes each row as a block of column | value lines headed by
+// its row number, rows are numbered from first.
+func renderExpanded(w io.Writer, header []string, rows [][]string, first int) error {
+	nameWidth, valueWidth := 0, 0
+	for _, name := range header {
+		if n := tablewriter.DisplayWidth(name); n > nameWidth {
+			nameWidth = n
+		}
+	}
+	for _, row := range rows {
+		for _, v := range row {
+			if n := tablewriter.DisplayWidth(v); n > valueWidth {
+				valueWidth = n
+			}
+		}
+	}
+
+	for i, row := range rows {
+		var b strings.Builder
+		fmt.Fprintf(&b, "\n@ Row %d\n", first+i)
+		b.WriteString(strings.Repeat("-", nameWidth+2) + "+" + strings.Repeat("-", valueWidth+2) + "\n")
+		for j, v := range row {
+			pad := strings.Repeat(" ", nameWidth-tablewriter.DisplayWidth(header[j]))
+			fmt.Fprintf(&b, " %s%s | %s\n", header[j], pad, v)
+		}
+
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+func TestExpandCommand(t *testing.T) {
+	c, out := newTestCQL()
+
+	tests := [...]struct {
+		stmt   string
+		output string
+		mode   expandMode
+	}{
+		{"EXPAND", "Expanded output is currently automatic.\n", expandAuto},
+		{"expand on", "Expanded output is now enabled.\n", expandOn},
+		{"EXPAND OFF;", "Expanded output is now disabled.\n", expandOff},
+		{"EXPAND", "Expanded output is currently disabled.\n", expandOff},
+		{"EXPAND auto", "Expanded output is now automatic.\n", expandAuto},
+	}
+
+	for _, test := range tests {
+		out.Reset()
+		if err := c.exec(test.stmt); err != nil {
+			t.Fatalf("%s: %v", test.stmt, err)
+		}
+
+		if out.String() != test.output {
+			t.Errorf("%s: expected output %q got %q", test.stmt, test.output, out.String())
+		} else if c.expand != test.mode {
+			t.Errorf("%s: expected mode %v got %v", test.stmt, test.mode, c.expand)
+		}
+	}
+
+	if err := c.exec("EXPAND sideways"); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+}
+
+func TestTableWidth(t *testing.T) {
+	header := []string{"id", "name"}
+	rows := [][]string{{"1", "\x1b[31mnull\x1b[0m"}, {"100", "bob"}}
+
+	var buf bytes.Buffer
+	table := tablewriter.NewWriter(&buf)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader(header)
+	table.AppendBulk(rows)
+	table.Render()
+
+	line := strings.SplitN(buf.String(), "\n", 2)[0]
+	if w := tableWidth(header, rows); w != len(line) {
+		t.Fatalf("expected width %d got %d", len(line), w)
+	}
+}
+
+func TestRenderExpanded(t *testing.T) {
+	var buf bytes.Buffer
+	header := []string{"id", "name"}
+	rows := [][]string{{"1", "alice"}, {"2", "null"}}
+	if err := renderExpanded(&buf, header, rows, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `
+@ Row 3
+------+-------
+ id   | 1
+ name | alice
+
+@ Row 4
+------+-------
+ id   | 2
+ name | null
+`
+	if buf.String() != exp {
+		t.Fatalf("expected:\n%s\ngot:\n%s", exp, buf.String())
+	}
+}
@@ -0,0 +1,358 @@
+package repl
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/gocql/gocqlsh/config"
+	"github.com/gocql/gocqlsh/metadata"
+
+	"github.com/logrusorgru/aurora"
+	"gopkg.in/inf.v0"
+)
+
+// formatter renders values returned by the driver for display, values nested
+// in collections, tuples and UDTs are written as CQL literals.
+type formatter struct {
+	timeZone        *time.Location
+	timeFormat      string
+	floatPrecision  int
+	doublePrecision int
+
+	au aurora.Aurora
+}
+
+func newFormatter(ui config.UI, au aurora.Aurora) *formatter {
+	return &formatter{
+		timeZone:        ui.TimeZone,
+		timeFormat:      ui.TimeFormat,
+		floatPrecision:  ui.FloatPrecision,
+		doublePrecision: ui.DoublePrecision,
+		au:              au,
+	}
+}
+
+// format returns v, which was unmarshalled from a value of type info, for
+// display in a result cell. A nil value, or a nil pointer, is null.
+func (f *formatter) format(info gocql.TypeInfo, v interface{}) string {
+	if isNull(v) {
+		return f.au.Red("null").String()
+	}
+
+	return f.value(info, v, false)
+}
+
+func (f *formatter) time(t time.Time) string {
+	return t.In(f.timeZone).Format(f.timeFormat)
+}
+
+func isNull(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+
+	// the driver unmarshals null collections and blobs as nil
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return rv.IsNil()
+	}
+
+	return false
+}
+
+// value formats v as type info, if nested is set strings and other quoted
+// types are written as CQL literals.
+func (f *formatter) value(info gocql.TypeInfo, v interface{}, nested bool) string {
+	if isNull(v) {
+		return "null"
+	} else if _, ok := v.(emptyValue); ok {
+		return ""
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	v = rv.Interface()
+
+	if info == nil {
+		return fmt.Sprint(v)
+	}
+
+	switch info.Type() {
+	case gocql.TypeAscii, gocql.TypeText, gocql.TypeVarchar:
+		s := fmt.Sprint(v)
+		if nested {
+			return quote(s)
+		}
+		return s
+	case gocql.TypeBlob, gocql.TypeCustom:
+		if b, ok := v.([]byte); ok {
+			return "0x" + hex.EncodeToString(b)
+		}
+	case gocql.TypeBoolean:
+		if b, ok := v.(bool); ok {
+			return strconv.FormatBool(b)
+		}
+	case gocql.TypeFloat:
+		if x, ok := v.(float32); ok {
+			return formatFloat(float64(x), f.floatPrecision, 32)
+		}
+	case gocql.TypeDouble:
+		if x, ok := v.(float64); ok {
+			return formatFloat(x, f.doublePrecision, 64)
+		}
+	case gocql.TypeDecimal:
+		switch x := v.(type) {
+		case inf.Dec:
+			return x.String()
+		}
+	case gocql.TypeVarint:
+		switch x := v.(type) {
+		case big.Int:
+			return x.String()
+		}
+	case gocql.TypeTimestamp:
+		if t, ok := v.(time.Time); ok {
+			return f.quoted(f.time(t), nested)
+		}
+	case gocql.TypeDate:
+		if t, ok := v.(time.Time); ok {
+			return f.quoted(t.UTC().Format("2006-01-02"), nested)
+		}
+	case gocql.TypeTime:
+		if d, ok := v.(time.Duration); ok {
+			return f.quoted(formatTimeOfDay(d), nested)
+		}
+	case gocql.TypeDuration:
+		if d, ok := v.(gocql.Duration); ok {
+			return formatDuration(d)
+		}
+	case gocql.TypeUUID, gocql.TypeTimeUUID:
+		if u, ok := v.(gocql.UUID); ok {
+			return u.String()
+		}
+	case gocql.TypeInet:
+		switch ip := v.(type) {
+		case net.IP:
+			return f.quoted(ip.String(), nested)
+		case string:
+			return f.quoted(ip, nested)
+		}
+	case gocql.TypeList, gocql.TypeSet:
+		elem := info.(gocql.CollectionType).Elem
+		open, close := "[", "]"
+		if info.Type() == gocql.TypeSet {
+			open, close = "{", "}"
+		}
+		return f.list(open, close, func(i int) (gocql.TypeInfo, interface{}) {
+			return elem, rv.Index(i).Interface()
+		}, rv)
+	case gocql.TypeMap:
+		return f.mapValue(info.(gocql.CollectionType), rv)
+	case gocql.TypeTuple:
+		elems := info.(gocql.TupleTypeInfo).Elems
+		if rv.Kind() != reflect.Slice {
+			break
+		}
+		return f.list("(", ")", func(i int) (gocql.TypeInfo, interface{}) {
+			if i < len(elems) {
+				return elems[i], rv.Index(i).Interface()
+			}
+			return nil, rv.Index(i).Interface()
+		}, rv)
+	case gocql.TypeUDT:
+		return f.udt(info.(gocql.UDTTypeInfo), v)
+	}
+
+	return fmt.Sprint(v)
+}
+
+func (f *formatter) quoted(s string, nested bool) string {
+	if nested {
+		return quote(s)
+	}
+	return s
+}
+
+func (f *formatter) list(open, close string, elem func(i int) (gocql.TypeInfo, interface{}), rv reflect.Value) string {
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return fmt.Sprint(rv.Interface())
+	}
+
+	items := make([]string, rv.Len())
+	for i := range items {
+		info, v := elem(i)
+		items[i] = f.value(info, v, true)
+	}
+
+	return open + strings.Join(items, ", ") + close
+}
+
+func (f *formatter) mapValue(info gocql.CollectionType, rv reflect.Value) string {
+	if entries, ok := rv.Interface().([]mapEntry); ok {
+		items := make([]string, len(entries))
+		for i, entry := range entries {
+			items[i] = f.value(info.Key, entry.key, true) + ": " + f.value(info.Elem, entry.value, true)
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+
+	if rv.Kind() != reflect.Map {
+		return fmt.Sprint(rv.Interface())
+	}
+
+	// Go maps are unordered, cassandra returns maps ordered by key
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return lessValue(keys[i], keys[j])
+	})
+
+	items := make([]string, len(keys))
+	for i, key := range keys {
+		items[i] = f.value(info.Key, key.Interface(), true) + ": " + f.value(info.Elem, rv.MapIndex(key).Interface(), true)
+	}
+
+	return "{" + strings.Join(items, ", ") + "}"
+}
+
+// udt formats v which is either a map of field names to values or a slice of
+// values in the order of the fields of info.
+func (f *formatter) udt(info gocql.UDTTypeInfo, v interface{}) string {
+	var field func(i int) interface{}
+	switch fields := v.(type) {
+	case map[string]interface{}:
+		field = func(i int) interface{} { return fields[info.Elements[i].Name] }
+	case []interface{}:
+		field = func(i int) interface{} {
+			if i < len(fields) {
+				return fields[i]
+			}
+			return nil
+		}
+	default:
+		return fmt.Sprint(v)
+	}
+
+	items := make([]string, len(info.Elements))
+	for i, elem := range info.Elements {
+		items[i] = quoteIdentifier(elem.Name) + ": " + f.value(elem.Type, field(i), true)
+	}
+
+	return "{" + strings.Join(items, ", ") + "}"
+}
+
+// lessValue orders map keys of the same type.
+func lessValue(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.String:
+		return a.String() < b.String()
+	}
+
+	switch x := a.Interface().(type) {
+	case time.Time:
+		return x.Before(b.Interface().(time.Time))
+	case *big.Int:
+		return x.Cmp(b.Interface().(*big.Int)) < 0
+	case *inf.Dec:
+		return x.Cmp(b.Interface().(*inf.Dec)) < 0
+	}
+
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
+
+func formatFloat(x float64, precision, bitSize int) string {
+	switch {
+	case math.IsNaN(x):
+		return "NaN"
+	case math.IsInf(x, 1):
+		return "Infinity"
+	case math.IsInf(x, -1):
+		return "-Infinity"
+	}
+
+	return strconv.FormatFloat(x, 'g', precision, bitSize)
+}
+
+// formatTimeOfDay formats d, nanoseconds since midnight, as a CQL time.
+func formatTimeOfDay(d time.Duration) string {
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+	d -= s * time.Second
+
+	return fmt.Sprintf("%02d:%02d:%02d.%09d", h, m, s, d)
+}
+
+// formatDuration formats d as a CQL duration literal, ie 1mo2d3h4m5s.
+func formatDuration(d gocql.Duration) string {
+	if d.Months == 0 && d.Days == 0 && d.Nanoseconds == 0 {
+		return "0s"
+	}
+
+	var b strings.Builder
+	if d.Months < 0 || d.Days < 0 || d.Nanoseconds < 0 {
+		b.WriteByte('-')
+	}
+
+	unit := func(n int64, suffix string) {
+		if n < 0 {
+			n = -n
+		}
+		if n != 0 {
+			b.WriteString(strconv.FormatInt(n, 10))
+			b.WriteString(suffix)
+		}
+	}
+
+	months := int64(d.Months)
+	unit(months/12, "y")
+	unit(months%12, "mo")
+	unit(int64(d.Days), "d")
+
+	nanos := d.Nanoseconds
+	for _, u := range [...]struct {
+		size   time.Duration
+		suffix string
+	}{
+		{time.Hour, "h"},
+		{time.Minute, "m"},
+		{time.Second, "s"},
+		{time.Millisecond, "ms"},
+		{time.Microsecond, "us"},
+		{time.Nanosecond, "ns"},
+	} {
+		unit(nanos/int64(u.size), u.suffix)
+		nanos %= int64(u.size)
+	}
+
+	return b.String()
+}
+
+// quote returns s as a CQL string literal.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// quoteIdentifier quotes name if it would not otherwise be read back as the
+// same identifier.
+func quoteIdentifier(name string) string {
+	return metadata.QuoteIdentifier(name)
+}
@@ -0,0 +1,98 @@
+package repl
+
+import (
+	"math"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/gocql/gocqlsh/config"
+
+	"github.com/logrusorgru/aurora"
+	"gopkg.in/inf.v0"
+)
+
+func native(typ gocql.Type) gocql.TypeInfo {
+	return gocql.NewNativeType(4, typ, "")
+}
+
+func collection(typ gocql.Type, key, elem gocql.TypeInfo) gocql.TypeInfo {
+	return gocql.CollectionType{
+		NativeType: gocql.NewNativeType(4, typ, ""),
+		Key:        key,
+		Elem:       elem,
+	}
+}
+
+func TestFormatter(t *testing.T) {
+	ui := config.Default().UI
+	ui.TimeZone = time.FixedZone("", 3600)
+	f := newFormatter(ui, aurora.NewAurora(false))
+
+	ts := time.Date(2017, 3, 4, 12, 30, 15, 123000000, time.UTC)
+	uuid, _ := gocql.ParseUUID("f2c993b9-6c2f-4137-a8f0-0fd5e5cc4433")
+	text := native(gocql.TypeVarchar)
+	integer := native(gocql.TypeInt)
+
+	udt := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(4, gocql.TypeUDT, ""),
+		Name:       "address",
+		Elements: []gocql.UDTField{
+			{Name: "street", Type: text},
+			{Name: "zipCode", Type: integer},
+			{Name: "tags", Type: collection(gocql.TypeSet, nil, text)},
+		},
+	}
+	tuple := gocql.TupleTypeInfo{
+		NativeType: gocql.NewNativeType(4, gocql.TypeTuple, ""),
+		Elems:      []gocql.TypeInfo{integer, text, native(gocql.TypeTimestamp)},
+	}
+
+	tests := [...]struct {
+		name string
+		info gocql.TypeInfo
+		v    interface{}
+		exp  string
+	}{
+		{"null", text, nil, "null"},
+		{"null pointer", native(gocql.TypeDecimal), (*inf.Dec)(nil), "null"},
+		{"text", text, "it's", "it's"},
+		{"int", integer, 42, "42"},
+		{"boolean", native(gocql.TypeBoolean), true, "true"},
+		{"blob", native(gocql.TypeBlob), []byte{0xca, 0xfe, 0x01}, "0xcafe01"},
+		{"empty blob", native(gocql.TypeBlob), []byte{}, "0x"},
+		{"float", native(gocql.TypeFloat), float32(1.123456), "1.1235"},
+		{"double", native(gocql.TypeDouble), 1234567.0, "1.2346e+06"},
+		{"nan", native(gocql.TypeDouble), math.NaN(), "NaN"},
+		{"infinity", native(gocql.TypeDouble), math.Inf(-1), "-Infinity"},
+		{"decimal", native(gocql.TypeDecimal), inf.NewDec(123456789012345, 10), "12345.6789012345"},
+		{"varint", native(gocql.TypeVarint), new(big.Int).Exp(big.NewInt(10), big.NewInt(30), nil), "1000000000000000000000000000000"},
+		{"timestamp", native(gocql.TypeTimestamp), ts, "2017-03-04 13:30:15.123+0100"},
+		{"date", native(gocql.TypeDate), ts, "2017-03-04"},
+		{"time", native(gocql.TypeTime), 13*time.Hour + 5*time.Minute + 7*time.Second + 8, "13:05:07.000000008"},
+		{"duration", native(gocql.TypeDuration), gocql.Duration{Months: 14, Days: 3, Nanoseconds: int64(90*time.Minute + time.Millisecond)}, "1y2mo3d1h30m1ms"},
+		{"negative duration", native(gocql.TypeDuration), gocql.Duration{Nanoseconds: -5}, "-5ns"},
+		{"uuid", native(gocql.TypeUUID), uuid, "f2c993b9-6c2f-4137-a8f0-0fd5e5cc4433"},
+		{"inet", native(gocql.TypeInet), net.IPv4(127, 0, 0, 1), "127.0.0.1"},
+		{"list", collection(gocql.TypeList, nil, text), []string{"a", "it's"}, "['a', 'it''s']"},
+		{"set", collection(gocql.TypeSet, nil, integer), []int{1, 2}, "{1, 2}"},
+		{"empty list", collection(gocql.TypeList, nil, integer), []int(nil), "null"},
+		{"map", collection(gocql.TypeMap, integer, text), map[int]string{10: "ten", 9: "nine"}, "{9: 'nine', 10: 'ten'}"},
+		{"map of lists", collection(gocql.TypeMap, text, collection(gocql.TypeList, nil, native(gocql.TypeTimestamp))),
+			map[string][]time.Time{"a": {ts}}, "{'a': ['2017-03-04 13:30:15.123+0100']}"},
+		{"tuple", tuple, []interface{}{1, "a", ts}, "(1, 'a', '2017-03-04 13:30:15.123+0100')"},
+		{"tuple with null", tuple, []interface{}{1, nil, ts}, "(1, null, '2017-03-04 13:30:15.123+0100')"},
+		{"udt", udt, map[string]interface{}{"street": "Main St", "zipCode": 1234, "tags": []string{"home"}},
+			`{street: 'Main St', "zipCode": 1234, tags: {'home'}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := f.format(test.info, test.v); got != test.exp {
+				t.Fatalf("expected %q got %q", test.exp, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package repl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocqlsh/cql/lexer"
+)
+
+const (
+	// maxHistory is the number of statements kept for HISTORY
+	maxHistory = 1000
+	// defaultHistory is the number of statements HISTORY lists by default
+	defaultHistory = 20
+)
+
+type historyEntry struct {
+	// n is the position of the statement in the session
+	n        int
+	stmt     string
+	started  time.Time
+	duration time.Duration
+	err      error
+}
+
+var lineBreak = regexp.MustCompile(`\s*\n\s*`)
+
+// oneLine joins the lines of a multi-line statement so that it is a single
+// line of history. Line comments are dropped as they would comment out the
+// rest of the statement. A statement with a line break in a string can not be
+// joined without changing it, ok is false for these.
+func oneLine(stmt string) (line string, ok bool) {
+	var b strings.Builder
+	rest := stmt
+	l := lexer.Lex(rest)
+	for rest != "" {
+		item := l.Item()
+		if item.Typ == lexer.ItemEOF {
+			// the rest of the input could not be lexed, ie it is not UTF-8
+			return "", false
+		}
+
+		// keywords are lowercased so the token is taken from the input
+		token := rest[:len(item.Val)]
+		rest = rest[len(token):]
+
+		switch {
+		case item.Typ == lexer.ItemComment && !strings.HasPrefix(token, "/*"):
+		case item.Typ == lexer.ItemError && strings.Contains(token, "--"):
+			// a line comment directly after a number is lexed as part of it,
+			// the rest of the line is skipped and lexed again from there
+			b.WriteString(token[:strings.Index(token, "--")])
+			if i := strings.IndexByte(rest, '\n'); i >= 0 {
+				rest = rest[i:]
+			} else {
+				rest = ""
+			}
+			l = lexer.Lex(rest)
+		case item.Typ == lexer.ItemWhitespace || item.Typ == lexer.ItemComment:
+			token = lineBreak.ReplaceAllString(token, " ")
+			if strings.HasSuffix(b.String(), " ") {
+				token = strings.TrimLeft(token, " ")
+			}
+			b.WriteString(token)
+		case strings.Contains(token, "\n"):
+			return "", false
+		default:
+			b.WriteString(token)
+		}
+	}
+	return strings.TrimSpace(b.String()), true
+}
+
+// sensitive reports whether any statement in input may contain a password,
+// these are never recorded in the history.
+func (c *CQL) sensitive(input string) bool {
+	if strings.Contains(strings.ToUpper(input), "PASSWORD") {
+		return true
+	}
+
+	for _, stmt := range splitStatements(input) {
+		if cmd, _, ok := c.commands.command(stmt.cql); ok && cmd.name == "LOGIN" {
+			return true
+		}
+	}
+	return false
+}
+
+// record adds a statement which has been executed to the history.
+func (c *CQL) record(stmt string, started time.Time, err error) {
+	if c.sensitive(stmt) {
+		return
+	}
+
+	line, ok := oneLine(stmt)
+	if !ok {
+		line = strings.TrimSpace(stmt)
+	}
+
+	c.executed++
+	c.history = append(c.history, historyEntry{
+		n:        c.executed,
+		stmt:     line,
+		started:  started,
+		duration: time.Since(started),
+		err:      err,
+	})
+
+	if len(c.history) > maxHistory {
+		c.history = append(c.history[:0], c.history[len(c.history)-maxHistory:]...)
+	}
+}
+
+// historyCommand handles HISTORY [n], listing the last n statements executed
+// in this session.
+func (c *CQL) historyCommand(_ string, args *lexer.Lexer) error {
+	n := defaultHistory
+	switch arg := args.ItemNoWS(); arg.Typ {
+	case lexer.ItemEOF, lexer.ItemSemiColon:
+	case lexer.ItemInteger:
+		var err error
+		if n, err = strconv.Atoi(arg.Val); err != nil || n <= 0 {
+			return fmt.Errorf("the number of statements must be a positive integer, got %s", arg.Val)
+		}
+	default:
+		return fmt.Errorf("usage: HISTORY [<n>]")
+	}
+
+	entries := c.history
+	if len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+
+	for _, entry := range entries {
+		status := c.au.Green("ok").String()
+		if entry.err != nil {
+			status = c.au.Red("error").String()
+		}
+
+		if _, err := fmt.Fprintf(c.out, "%4d  %s  %8s  %s  %s\n", entry.n, c.format.time(entry.started),
+			entry.duration.Round(time.Millisecond), status, entry.stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
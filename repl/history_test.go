@@ -0,0 +1,86 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	c, out := newTestCQL()
+	c.format.timeZone = time.UTC
+
+	if err := c.Execute("PAGING 10;\nEXPAND\n  ON;\nPAGING nope;", true); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// statements which may contain passwords are not recorded
+	for _, stmt := range []string{"LOGIN bob secret", "CREATE ROLE bob WITH password = 'x'"} {
+		if !c.sensitive(stmt) {
+			t.Errorf("%s: expected statement to be sensitive", stmt)
+		}
+		c.record(stmt, time.Now(), nil)
+	}
+
+	out.Reset()
+	if err := c.Execute("HISTORY", false); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 statements got %q", lines)
+	}
+
+	for i, exp := range [...]struct {
+		n      string
+		status string
+		stmt   string
+	}{
+		{"1", "ok", "PAGING 10"},
+		{"2", "ok", "EXPAND ON"},
+		{"3", "error", "PAGING nope"},
+	} {
+		fields := strings.Fields(lines[i])
+		if fields[0] != exp.n || !strings.Contains(lines[i], " "+exp.status+"  "+exp.stmt) {
+			t.Errorf("expected statement %s %s %q got %q", exp.n, exp.status, exp.stmt, lines[i])
+		}
+	}
+
+	out.Reset()
+	if err := c.Execute("HISTORY 1", false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "   4  ") || !strings.HasSuffix(out.String(), "HISTORY\n") {
+		t.Fatalf("expected only the last statement got %q", out.String())
+	}
+
+	if err := c.exec("HISTORY 0"); err == nil {
+		t.Fatal("expected error for HISTORY 0")
+	}
+}
+
+func TestOneLine(t *testing.T) {
+	tests := [...]struct {
+		stmt string
+		exp  string
+		ok   bool
+	}{
+		{"SELECT *\n  FROM events\n;", "SELECT * FROM events ;", true},
+		// line comments would comment out the rest of the joined statement
+		{"SELECT * -- every column\nFROM events // all of them\n  WHERE id = 1", "SELECT * FROM events WHERE id = 1", true},
+		{"SELECT * FROM events LIMIT 10-- it's enough\n;", "SELECT * FROM events LIMIT 10 ;", true},
+		{"SELECT * FROM events WHERE n = -1--last\nLIMIT 1", "SELECT * FROM events WHERE n = -1 LIMIT 1", true},
+		{"SELECT * /* every\ncolumn */ FROM events", "SELECT * /* every column */ FROM events", true},
+		{"INSERT INTO notes (id, body) VALUES (1, 'a -- b')", "INSERT INTO notes (id, body) VALUES (1, 'a -- b')", true},
+		// line breaks in strings are part of the value
+		{"INSERT INTO notes (id, body)\nVALUES (1, 'first\n  second')", "", false},
+		{"CREATE FUNCTION f(a int) RETURNS NULL ON NULL INPUT RETURNS int LANGUAGE java\nAS $$\n  return a;\n$$;", "", false},
+	}
+
+	for _, test := range tests {
+		if got, ok := oneLine(test.stmt); got != test.exp || ok != test.ok {
+			t.Errorf("%q: expected %q %v got %q %v", test.stmt, test.exp, test.ok, got, ok)
+		}
+	}
+}
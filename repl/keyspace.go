@@ -0,0 +1,51 @@
+package repl
+
+import (
+	"fmt"
+
+	"github.com/gocql/gocqlsh/cql/lexer"
+)
+
+// useCommand handles USE <keyspace>. Sent to the server USE only changes the
+// keyspace of the connection it is executed on, so instead the session is
+// recreated with the keyspace set for every connection.
+func (c *CQL) useCommand(_ string, args *lexer.Lexer) error {
+	item := args.ItemNoWS()
+	if !isIdentifier(item) && item.Typ != lexer.ItemString {
+		return fmt.Errorf("usage: USE <keyspace>")
+	}
+
+	keyspace := identifier(item)
+	if item.Typ == lexer.ItemString {
+		keyspace = unquote(item)
+	}
+
+	if end := args.ItemNoWS(); end.Typ != lexer.ItemEOF && end.Typ != lexer.ItemSemiColon {
+		return fmt.Errorf("usage: USE <keyspace>")
+	}
+
+	if keyspace == c.keyspace() {
+		return nil
+	}
+
+	cluster := *c.cluster
+	cluster.Keyspace = keyspace
+	if err := c.reconnect(&cluster); err != nil {
+		return fmt.Errorf("unable to use keyspace %s: %v", quoteIdentifier(keyspace), err)
+	}
+	return nil
+}
+
+// keyspace returns the current keyspace or an empty string if there is none.
+func (c *CQL) keyspace() string {
+	return c.cluster.Keyspace
+}
+
+// prompt returns the prompt for a new statement, including the current
+// keyspace if there is one.
+func (c *CQL) prompt() string {
+	if ks := c.keyspace(); ks != "" {
+		return "gocqlsh:" + ks + "> "
+	}
+	return prompt
+}
@@ -0,0 +1,37 @@
+package repl
+
+import "testing"
+
+func TestPrompt(t *testing.T) {
+	c, _ := newTestCQL()
+	if p := c.prompt(); p != "gocqlsh> " {
+		t.Errorf("expected prompt without keyspace got %q", p)
+	}
+
+	c.cluster.Keyspace = "Events"
+	if p := c.prompt(); p != "gocqlsh:Events> " {
+		t.Errorf("expected prompt with keyspace got %q", p)
+	}
+}
+
+func TestUseCommand(t *testing.T) {
+	c, out := newTestCQL()
+	c.cluster.Keyspace = "events"
+
+	for _, stmt := range []string{"USE", "USE ;", "USE events extra", "USE 1"} {
+		if err := c.exec(stmt); err == nil {
+			t.Errorf("%s: expected usage error", stmt)
+		}
+	}
+
+	// using the current keyspace does not reconnect
+	for _, stmt := range []string{"USE events", "use EVENTS;", "USE 'events'"} {
+		if err := c.exec(stmt); err != nil {
+			t.Errorf("%s: %v", stmt, err)
+		}
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("expected no output got %q", out.String())
+	}
+}
@@ -0,0 +1,386 @@
+package repl
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/gocql/gocqlsh/cql/lexer"
+)
+
+// outputFormat is how query results are written, the default table format is
+// meant to be read and the others to be read by other programs.
+type outputFormat string
+
+const (
+	formatTable    outputFormat = "table"
+	formatJSON     outputFormat = "json"
+	formatNDJSON   outputFormat = "ndjson"
+	formatCSV      outputFormat = "csv"
+	formatTSV      outputFormat = "tsv"
+	formatMarkdown outputFormat = "markdown"
+)
+
+var outputFormats = [...]outputFormat{formatTable, formatJSON, formatNDJSON, formatCSV, formatTSV, formatMarkdown}
+
+func parseOutputFormat(name string) (outputFormat, error) {
+	for _, format := range outputFormats {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+	}
+
+	names := make([]string, len(outputFormats))
+	for i, format := range outputFormats {
+		names[i] = string(format)
+	}
+	return "", fmt.Errorf("unknown output format %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// CheckFormat returns an error if name is not a known output format.
+func CheckFormat(name string) error {
+	_, err := parseOutputFormat(name)
+	return err
+}
+
+func (c *CQL) formatCommand(_ string, args *lexer.Lexer) error {
+	arg := args.ItemNoWS()
+	if arg.Typ == lexer.ItemEOF || arg.Typ == lexer.ItemSemiColon {
+		_, err := fmt.Fprintf(c.out, "Output format is currently %s.\n", c.output)
+		return err
+	}
+
+	format, err := parseOutputFormat(unquote(arg))
+	if err != nil {
+		return err
+	}
+
+	c.output = format
+	_, err = fmt.Fprintf(c.out, "Now using %s output format.\n", format)
+	return err
+}
+
+// rowWriter writes rows of values decoded by a rowReader.
+type rowWriter interface {
+	row(values []interface{}) error
+	// close writes anything remaining after the last row.
+	close() error
+}
+
+func (c *CQL) newRowWriter(w io.Writer, columns []gocql.ColumnInfo) (rowWriter, error) {
+	switch c.output {
+	case formatJSON, formatNDJSON:
+		return &jsonWriter{w: w, f: c.format, columns: columns, array: c.output == formatJSON}, nil
+	case formatCSV, formatTSV:
+		cw := csv.NewWriter(w)
+		if c.output == formatTSV {
+			cw.Comma = '\t'
+		}
+		return newCSVWriter(cw, c.format, columns)
+	case formatMarkdown:
+		return newMarkdownWriter(w, c.format, columns)
+	}
+
+	return nil, fmt.Errorf("no row writer for %s output", c.output)
+}
+
+// writeRows writes the rows remaining in iter in the current output format,
+// returning the number of rows written.
+func (c *CQL) writeRows(iter *gocql.Iter) (int, error) {
+	columns := iter.Columns()
+	if len(columns) == 0 {
+		// statements which do not return rows
+		return 0, nil
+	}
+
+	w, err := c.newRowWriter(c.out, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	rows := newRowReader(columns)
+	for rows.next(iter) {
+		if err := w.row(rows.row); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if rows.err != nil {
+		return n, rows.err
+	}
+
+	return n, w.close()
+}
+
+// jsonWriter writes each row as an object keyed by column name, either as
+// the elements of an array or one per line.
+type jsonWriter struct {
+	w       io.Writer
+	f       *formatter
+	columns []gocql.ColumnInfo
+	array   bool
+	rows    int
+}
+
+func (j *jsonWriter) row(values []interface{}) error {
+	obj := make(jsonObject, len(j.columns))
+	for i, col := range j.columns {
+		obj[i] = jsonField{col.Name, j.f.jsonValue(col.TypeInfo, values[i])}
+	}
+
+	b, err := marshalJSON(obj)
+	if err != nil {
+		return err
+	}
+
+	var line string
+	switch {
+	case !j.array:
+		line = string(b) + "\n"
+	case j.rows == 0:
+		line = "[\n" + string(b)
+	default:
+		line = ",\n" + string(b)
+	}
+	j.rows++
+
+	_, err = io.WriteString(j.w, line)
+	return err
+}
+
+func (j *jsonWriter) close() error {
+	if !j.array {
+		return nil
+	}
+
+	end := "\n]\n"
+	if j.rows == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
+
+// jsonObject is a JSON object which keeps the order of its fields.
+type jsonObject []jsonField
+
+type jsonField struct {
+	name  string
+	value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		name, err := marshalJSON(field.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalJSON(field.value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// marshalJSON is json.Marshal without escaping HTML characters, which would
+// otherwise make text values harder to read.
+func marshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// jsonValue returns v, which was decoded as type info, as a value to be
+// marshalled as JSON. Numbers and booleans are kept as is, collections,
+// tuples and UDTs become arrays and objects and other types are formatted as
+// strings.
+func (f *formatter) jsonValue(info gocql.TypeInfo, v interface{}) interface{} {
+	if isNull(v) {
+		return nil
+	} else if _, ok := v.(emptyValue); ok {
+		return ""
+	}
+
+	switch info.Type() {
+	case gocql.TypeTinyInt, gocql.TypeSmallInt, gocql.TypeInt, gocql.TypeBigInt, gocql.TypeCounter,
+		gocql.TypeBoolean:
+		return v
+	case gocql.TypeFloat, gocql.TypeDouble:
+		// JSON has no representation of NaN or infinity
+		var x float64
+		switch n := v.(type) {
+		case float32:
+			x = float64(n)
+		case float64:
+			x = n
+		}
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return f.value(info, v, false)
+		}
+		return v
+	case gocql.TypeDecimal, gocql.TypeVarint:
+		return json.Number(f.value(info, v, false))
+	case gocql.TypeTimestamp:
+		if t, ok := v.(time.Time); ok {
+			return t.In(f.timeZone).Format(time.RFC3339Nano)
+		}
+	case gocql.TypeList, gocql.TypeSet:
+		items, ok := v.([]interface{})
+		if !ok {
+			break
+		}
+		elem := info.(gocql.CollectionType).Elem
+		values := make([]interface{}, len(items))
+		for i, item := range items {
+			values[i] = f.jsonValue(elem, item)
+		}
+		return values
+	case gocql.TypeMap:
+		entries, ok := v.([]mapEntry)
+		if !ok {
+			break
+		}
+		coll := info.(gocql.CollectionType)
+		obj := make(jsonObject, len(entries))
+		for i, entry := range entries {
+			obj[i] = jsonField{f.value(coll.Key, entry.key, false), f.jsonValue(coll.Elem, entry.value)}
+		}
+		return obj
+	case gocql.TypeTuple:
+		elems, ok := v.([]interface{})
+		if !ok {
+			break
+		}
+		types := info.(gocql.TupleTypeInfo).Elems
+		values := make([]interface{}, len(elems))
+		for i, elem := range elems {
+			values[i] = f.jsonValue(types[i], elem)
+		}
+		return values
+	case gocql.TypeUDT:
+		fields, ok := v.([]interface{})
+		if !ok {
+			break
+		}
+		elems := info.(gocql.UDTTypeInfo).Elements
+		obj := make(jsonObject, len(elems))
+		for i, elem := range elems {
+			obj[i] = jsonField{name: elem.Name}
+			if i < len(fields) {
+				obj[i].value = f.jsonValue(elem.Type, fields[i])
+			}
+		}
+		return obj
+	}
+
+	return f.value(info, v, false)
+}
+
+// csvWriter writes rows as comma or tab separated values with a header of the
+// column names, null values are empty.
+type csvWriter struct {
+	w       *csv.Writer
+	f       *formatter
+	columns []gocql.ColumnInfo
+	record  []string
+}
+
+func newCSVWriter(w *csv.Writer, f *formatter, columns []gocql.ColumnInfo) (*csvWriter, error) {
+	c := &csvWriter{w: w, f: f, columns: columns, record: make([]string, len(columns))}
+	for i, col := range columns {
+		c.record[i] = col.Name
+	}
+
+	if err := w.Write(c.record); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *csvWriter) row(values []interface{}) error {
+	for i, col := range c.columns {
+		c.record[i] = ""
+		if !isNull(values[i]) {
+			c.record[i] = c.f.value(col.TypeInfo, values[i], false)
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// markdownWriter writes rows as a GitHub flavoured markdown table.
+type markdownWriter struct {
+	w       io.Writer
+	f       *formatter
+	columns []gocql.ColumnInfo
+}
+
+func newMarkdownWriter(w io.Writer, f *formatter, columns []gocql.ColumnInfo) (*markdownWriter, error) {
+	m := &markdownWriter{w: w, f: f, columns: columns}
+
+	header := make([]string, len(columns))
+	rule := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = markdownEscape(col.Name)
+		rule[i] = "---"
+	}
+
+	if err := m.line(header); err != nil {
+		return nil, err
+	}
+	if err := m.line(rule); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *markdownWriter) line(cells []string) error {
+	_, err := io.WriteString(m.w, "| "+strings.Join(cells, " | ")+" |\n")
+	return err
+}
+
+func (m *markdownWriter) row(values []interface{}) error {
+	cells := make([]string, len(m.columns))
+	for i, col := range m.columns {
+		cells[i] = markdownEscape(m.f.value(col.TypeInfo, values[i], false))
+	}
+	return m.line(cells)
+}
+
+func (m *markdownWriter) close() error {
+	return nil
+}
+
+var markdownReplacer = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r\n", "<br>", "\n", "<br>")
+
+func markdownEscape(s string) string {
+	return markdownReplacer.Replace(s)
+}
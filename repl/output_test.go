@@ -0,0 +1,120 @@
+package repl
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+func TestFormatCommand(t *testing.T) {
+	c, out := newTestCQL()
+
+	tests := [...]struct {
+		stmt   string
+		output string
+		format outputFormat
+	}{
+		{"FORMAT", "Output format is currently table.\n", formatTable},
+		{"format json", "Now using json output format.\n", formatJSON},
+		{"FORMAT 'CSV';", "Now using csv output format.\n", formatCSV},
+		{"FORMAT", "Output format is currently csv.\n", formatCSV},
+		{"FORMAT table", "Now using table output format.\n", formatTable},
+	}
+
+	for _, test := range tests {
+		out.Reset()
+		if err := c.exec(test.stmt); err != nil {
+			t.Fatalf("%s: %v", test.stmt, err)
+		}
+
+		if out.String() != test.output {
+			t.Errorf("%s: expected output %q got %q", test.stmt, test.output, out.String())
+		} else if c.output != test.format {
+			t.Errorf("%s: expected format %v got %v", test.stmt, test.format, c.output)
+		}
+	}
+
+	if err := c.exec("FORMAT yaml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestRowWriters(t *testing.T) {
+	text := native(gocql.TypeVarchar)
+	columns := []gocql.ColumnInfo{
+		{Name: "id", TypeInfo: native(gocql.TypeInt)},
+		{Name: "name", TypeInfo: text},
+		{Name: "score", TypeInfo: native(gocql.TypeDouble)},
+		{Name: "tags", TypeInfo: collection(gocql.TypeMap, text, native(gocql.TypeBigInt))},
+		{Name: "seen", TypeInfo: native(gocql.TypeTimestamp)},
+	}
+	rows := [][]interface{}{
+		{1, "a, \"b\" | <c>", 1.5, []mapEntry{{"z", int64(1)}, {"a", nil}},
+			time.Date(2017, 3, 4, 12, 30, 15, 0, time.UTC)},
+		{2, nil, math.NaN(), nil, nil},
+	}
+
+	tests := [...]struct {
+		format outputFormat
+		exp    string
+	}{
+		{formatJSON, `[
+{"id":1,"name":"a, \"b\" | <c>","score":1.5,"tags":{"z":1,"a":null},"seen":"2017-03-04T12:30:15Z"},
+{"id":2,"name":null,"score":"NaN","tags":null,"seen":null}
+]
+`},
+		{formatNDJSON, `{"id":1,"name":"a, \"b\" | <c>","score":1.5,"tags":{"z":1,"a":null},"seen":"2017-03-04T12:30:15Z"}
+{"id":2,"name":null,"score":"NaN","tags":null,"seen":null}
+`},
+		{formatCSV, `id,name,score,tags,seen
+1,"a, ""b"" | <c>",1.5,"{'z': 1, 'a': null}",2017-03-04 12:30:15.000+0000
+2,,NaN,,
+`},
+		{formatTSV, "id\tname\tscore\ttags\tseen\n" +
+			"1\t\"a, \"\"b\"\" | <c>\"\t1.5\t{'z': 1, 'a': null}\t2017-03-04 12:30:15.000+0000\n" +
+			"2\t\tNaN\t\t\n"},
+		{formatMarkdown, `| id | name | score | tags | seen |
+| --- | --- | --- | --- | --- |
+| 1 | a, "b" \| <c> | 1.5 | {'z': 1, 'a': null} | 2017-03-04 12:30:15.000+0000 |
+| 2 | null | NaN | null | null |
+`},
+	}
+
+	for _, test := range tests {
+		c, _ := newTestCQL()
+		c.format.timeZone = time.UTC
+		c.output = test.format
+
+		var buf bytes.Buffer
+		w, err := c.newRowWriter(&buf, columns)
+		if err != nil {
+			t.Fatalf("%s: %v", test.format, err)
+		}
+		for _, row := range rows {
+			if err := w.row(row); err != nil {
+				t.Fatalf("%s: %v", test.format, err)
+			}
+		}
+		if err := w.close(); err != nil {
+			t.Fatalf("%s: %v", test.format, err)
+		}
+
+		if buf.String() != test.exp {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", test.format, test.exp, buf.String())
+		}
+	}
+
+	// an empty result is still a valid JSON document
+	c, _ := newTestCQL()
+	c.output = formatJSON
+	var buf bytes.Buffer
+	w, _ := c.newRowWriter(&buf, columns)
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	} else if buf.String() != "[]\n" {
+		t.Fatalf("expected empty array got %q", buf.String())
+	}
+}
@@ -0,0 +1,76 @@
+package repl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gocql/gocqlsh/cql/lexer"
+
+	"github.com/chzyer/readline"
+)
+
+const defaultPageSize = 100
+
+func (c *CQL) pagingCommand(_ string, args *lexer.Lexer) error {
+	arg := args.ItemNoWS()
+	switch {
+	case arg.Typ == lexer.ItemEOF || arg.Typ == lexer.ItemSemiColon:
+		if c.pageSize == 0 {
+			_, err := fmt.Fprintln(c.out, "Query paging is currently disabled.")
+			return err
+		}
+		_, err := fmt.Fprintf(c.out, "Query paging is currently enabled with a page size of %d.\n", c.pageSize)
+		return err
+	case arg.Typ == lexer.ItemKeyword && arg.Val == "on":
+		if c.pageSize == 0 {
+			c.pageSize = defaultPageSize
+		}
+	case strings.EqualFold(arg.Val, "off"):
+		c.pageSize = 0
+		_, err := fmt.Fprintln(c.out, "Disabled query paging.")
+		return err
+	case arg.Typ == lexer.ItemInteger:
+		n, err := strconv.Atoi(arg.Val)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("page size must be a positive integer, got %s", arg.Val)
+		}
+		c.pageSize = n
+	default:
+		return fmt.Errorf("usage: PAGING [ON | OFF | <page size>]")
+	}
+
+	_, err := fmt.Fprintf(c.out, "Now query paging is enabled with a page size of %d.\n", c.pageSize)
+	return err
+}
+
+// more prompts the user to continue on to the next page of results, space or
+// enter continue and q stops.
+func (c *CQL) more() (bool, error) {
+	quit := false
+	c.r.Config.FuncFilterInputRune = func(r rune) (rune, bool) {
+		switch r {
+		case ' ', readline.CharEnter:
+			return readline.CharEnter, true
+		case 'q', 'Q':
+			quit = true
+			return readline.CharEnter, true
+		case readline.CharInterrupt:
+			return r, true
+		}
+		// swallow any other keys
+		return r, false
+	}
+	defer func() {
+		c.r.Config.FuncFilterInputRune = nil
+	}()
+
+	c.r.SetPrompt(c.au.Bold("---MORE---").String())
+	if _, err := c.r.Readline(); err == readline.ErrInterrupt {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return !quit, nil
+}
@@ -0,0 +1,39 @@
+package repl
+
+import "testing"
+
+func TestPagingCommand(t *testing.T) {
+	c, out := newTestCQL()
+
+	tests := [...]struct {
+		stmt     string
+		output   string
+		pageSize int
+	}{
+		{"PAGING", "Query paging is currently enabled with a page size of 100.\n", 100},
+		{"paging off", "Disabled query paging.\n", 0},
+		{"PAGING", "Query paging is currently disabled.\n", 0},
+		{"PAGING ON;", "Now query paging is enabled with a page size of 100.\n", 100},
+		{"PAGING 20", "Now query paging is enabled with a page size of 20.\n", 20},
+		{"PAGING ON", "Now query paging is enabled with a page size of 20.\n", 20},
+	}
+
+	for _, test := range tests {
+		out.Reset()
+		if err := c.exec(test.stmt); err != nil {
+			t.Fatalf("%s: %v", test.stmt, err)
+		}
+
+		if out.String() != test.output {
+			t.Errorf("%s: expected output %q got %q", test.stmt, test.output, out.String())
+		} else if c.pageSize != test.pageSize {
+			t.Errorf("%s: expected page size %d got %d", test.stmt, test.pageSize, c.pageSize)
+		}
+	}
+
+	for _, stmt := range []string{"PAGING 0", "PAGING -1", "PAGING sometimes"} {
+		if err := c.exec(stmt); err == nil {
+			t.Errorf("%s: expected error", stmt)
+		}
+	}
+}
@@ -0,0 +1,461 @@
+package repl
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+
+	"gopkg.in/inf.v0"
+)
+
+// timestampLayouts are the layouts accepted for timestamps in addition to the
+// configured display format.
+var timestampLayouts = [...]string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-0700",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04-0700",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parse is the inverse of format, it parses s, a value written as it is
+// displayed, as type info into a value which can be marshalled by the driver.
+// Collections and tuples are written as CQL literals.
+func (f *formatter) parse(info gocql.TypeInfo, s string) (interface{}, error) {
+	switch info.Type() {
+	case gocql.TypeList, gocql.TypeSet, gocql.TypeMap, gocql.TypeTuple, gocql.TypeUDT:
+		p := &literalParser{f: f, s: s}
+		v, err := p.value(info)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.space(); p.pos < len(p.s) {
+			return nil, fmt.Errorf("unexpected %q after %s value", p.s[p.pos:], info)
+		}
+		return v, nil
+	}
+
+	return f.parseScalar(info, s)
+}
+
+func (f *formatter) parseScalar(info gocql.TypeInfo, s string) (interface{}, error) {
+	switch info.Type() {
+	case gocql.TypeAscii, gocql.TypeText, gocql.TypeVarchar:
+		return s, nil
+	}
+
+	s = strings.TrimSpace(s)
+	switch info.Type() {
+	case gocql.TypeTinyInt, gocql.TypeSmallInt, gocql.TypeInt, gocql.TypeBigInt:
+		bits := map[gocql.Type]int{
+			gocql.TypeTinyInt:  8,
+			gocql.TypeSmallInt: 16,
+			gocql.TypeInt:      32,
+			gocql.TypeBigInt:   64,
+		}[info.Type()]
+		return strconv.ParseInt(s, 10, bits)
+	case gocql.TypeFloat, gocql.TypeDouble:
+		var x float64
+		switch s {
+		case "NaN":
+			x = math.NaN()
+		case "Infinity":
+			x = math.Inf(1)
+		case "-Infinity":
+			x = math.Inf(-1)
+		default:
+			bits := 64
+			if info.Type() == gocql.TypeFloat {
+				bits = 32
+			}
+
+			var err error
+			if x, err = strconv.ParseFloat(s, bits); err != nil {
+				return nil, err
+			}
+		}
+
+		if info.Type() == gocql.TypeFloat {
+			return float32(x), nil
+		}
+		return x, nil
+	case gocql.TypeBoolean:
+		return strconv.ParseBool(strings.ToLower(s))
+	case gocql.TypeDecimal:
+		d, ok := new(inf.Dec).SetString(s)
+		if !ok {
+			return nil, fmt.Errorf("invalid decimal %q", s)
+		}
+		return *d, nil
+	case gocql.TypeVarint:
+		n, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid varint %q", s)
+		}
+		return n, nil
+	case gocql.TypeBlob:
+		if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+			return nil, fmt.Errorf("invalid blob %q, expected a 0x prefixed hex string", s)
+		}
+		return hex.DecodeString(s[2:])
+	case gocql.TypeUUID, gocql.TypeTimeUUID:
+		return gocql.ParseUUID(s)
+	case gocql.TypeInet:
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid inet address %q", s)
+		}
+		return ip, nil
+	case gocql.TypeTimestamp:
+		return f.parseTimestamp(s)
+	case gocql.TypeDate:
+		return time.ParseInLocation("2006-01-02", s, time.UTC)
+	case gocql.TypeTime:
+		return parseTimeOfDay(s)
+	case gocql.TypeDuration:
+		return parseDuration(s)
+	}
+
+	return nil, fmt.Errorf("unable to parse values of type %s", info)
+}
+
+// parseTimestamp parses s in the display format, one of the common layouts
+// in timestampLayouts or as milliseconds since the epoch.
+func (f *formatter) parseTimestamp(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation(f.timeFormat, s, f.timeZone); err == nil {
+		return t, nil
+	}
+
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, s, f.timeZone); err == nil {
+			return t, nil
+		}
+	}
+
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(0, ms*int64(time.Millisecond)), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+}
+
+// parseTimeOfDay parses a CQL time, hh:mm:ss[.fffffffff].
+func parseTimeOfDay(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid time %q, expected hh:mm:ss[.fffffffff]", s)
+	}
+
+	secs, frac := parts[2], ""
+	if i := strings.IndexByte(secs, '.'); i >= 0 {
+		secs, frac = secs[:i], secs[i+1:]
+	}
+	if len(frac) > 9 {
+		return 0, fmt.Errorf("invalid time %q, more than nanosecond precision", s)
+	}
+
+	var d time.Duration
+	for i, part := range [...]struct {
+		s     string
+		unit  time.Duration
+		limit int64
+	}{
+		{parts[0], time.Hour, 24},
+		{parts[1], time.Minute, 60},
+		{secs, time.Second, 60},
+		{frac + strings.Repeat("0", 9-len(frac)), time.Nanosecond, 1e9},
+	} {
+		n, err := strconv.ParseInt(part.s, 10, 64)
+		if err != nil || n < 0 || n >= part.limit || (i < 3 && part.s == "") {
+			return 0, fmt.Errorf("invalid time %q", s)
+		}
+		d += time.Duration(n) * part.unit
+	}
+
+	return d, nil
+}
+
+// durationUnits are the units of a CQL duration literal, longest suffix first
+// so that ie ms is not read as m.
+var durationUnits = [...]struct {
+	suffix string
+	months int64
+	days   int64
+	nanos  int64
+}{
+	{"mo", 1, 0, 0},
+	{"ms", 0, 0, int64(time.Millisecond)},
+	{"us", 0, 0, int64(time.Microsecond)},
+	{"ns", 0, 0, 1},
+	{"y", 12, 0, 0},
+	{"w", 0, 7, 0},
+	{"d", 0, 1, 0},
+	{"h", 0, 0, int64(time.Hour)},
+	{"m", 0, 0, int64(time.Minute)},
+	{"s", 0, 0, int64(time.Second)},
+}
+
+// parseDuration parses a CQL duration literal as written by formatDuration,
+// ie 1y2mo3d4h5m6s.
+func parseDuration(s string) (gocql.Duration, error) {
+	in := s
+	var d gocql.Duration
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	if s == "" {
+		return d, fmt.Errorf("invalid duration %q", in)
+	}
+
+	var months, days, nanos int64
+	for s != "" {
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == 0 {
+			return d, fmt.Errorf("invalid duration %q", in)
+		}
+
+		n, err := strconv.ParseInt(s[:i], 10, 64)
+		if err != nil {
+			return d, fmt.Errorf("invalid duration %q: %v", in, err)
+		}
+		s = s[i:]
+
+		found := false
+		for _, unit := range durationUnits {
+			if strings.HasPrefix(strings.ToLower(s), unit.suffix) {
+				months += n * unit.months
+				days += n * unit.days
+				nanos += n * unit.nanos
+				s = s[len(unit.suffix):]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return d, fmt.Errorf("invalid duration %q, unknown unit", in)
+		}
+	}
+
+	if neg {
+		months, days, nanos = -months, -days, -nanos
+	}
+	if months > math.MaxInt32 || months < math.MinInt32 || days > math.MaxInt32 || days < math.MinInt32 {
+		return d, fmt.Errorf("invalid duration %q, out of range", in)
+	}
+
+	return gocql.Duration{Months: int32(months), Days: int32(days), Nanoseconds: nanos}, nil
+}
+
+// literalParser parses CQL collection, tuple and UDT literals such as
+// {'a': [1, 2]}.
+type literalParser struct {
+	f   *formatter
+	s   string
+	pos int
+}
+
+func (p *literalParser) space() {
+	for p.pos < len(p.s) && strings.ContainsRune(" \t\r\n", rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+// accept consumes c if it is the next character other than whitespace.
+func (p *literalParser) accept(c byte) bool {
+	if p.space(); p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *literalParser) expect(c byte) error {
+	if !p.accept(c) {
+		return p.errorf("expected %q", c)
+	}
+	return nil
+}
+
+func (p *literalParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid literal %q at offset %d: %s", p.s, p.pos, fmt.Sprintf(format, args...))
+}
+
+// token returns the next scalar, a quoted string with the quotes removed or
+// the text up to the next delimiter. quoted reports if the value was quoted.
+func (p *literalParser) token() (s string, quoted bool, err error) {
+	p.space()
+	if p.pos < len(p.s) && (p.s[p.pos] == '\'' || p.s[p.pos] == '"') {
+		quote := p.s[p.pos]
+		var b strings.Builder
+		for p.pos++; p.pos < len(p.s); p.pos++ {
+			if p.s[p.pos] == quote {
+				if p.pos+1 < len(p.s) && p.s[p.pos+1] == quote {
+					p.pos++
+				} else {
+					p.pos++
+					return b.String(), true, nil
+				}
+			}
+			b.WriteByte(p.s[p.pos])
+		}
+		return "", false, p.errorf("unterminated string")
+	}
+
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(",:)]}", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	if s = strings.TrimSpace(p.s[start:p.pos]); s == "" {
+		return "", false, p.errorf("expected a value")
+	}
+	return s, false, nil
+}
+
+// sequence calls elem for each comma separated element up to close.
+func (p *literalParser) sequence(open, close byte, elem func(i int) error) error {
+	if err := p.expect(open); err != nil {
+		return err
+	}
+	if p.accept(close) {
+		return nil
+	}
+
+	for i := 0; ; i++ {
+		if err := elem(i); err != nil {
+			return err
+		}
+		if p.accept(close) {
+			return nil
+		}
+		if err := p.expect(','); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *literalParser) value(info gocql.TypeInfo) (interface{}, error) {
+	p.space()
+	if rest := p.s[p.pos:]; len(rest) >= 4 && strings.EqualFold(rest[:4], "null") &&
+		(len(rest) == 4 || strings.ContainsRune(" \t\r\n,:)]}", rune(rest[4]))) {
+		p.pos += 4
+		return nil, nil
+	}
+
+	switch info.Type() {
+	case gocql.TypeList, gocql.TypeSet:
+		elem := info.(gocql.CollectionType).Elem
+		open, close := byte('['), byte(']')
+		if info.Type() == gocql.TypeSet {
+			open, close = '{', '}'
+		}
+
+		items := []interface{}{}
+		err := p.sequence(open, close, func(int) error {
+			v, err := p.value(elem)
+			items = append(items, v)
+			return err
+		})
+		return items, err
+	case gocql.TypeMap:
+		coll := info.(gocql.CollectionType)
+		m := map[interface{}]interface{}{}
+		err := p.sequence('{', '}', func(int) error {
+			key, err := p.value(coll.Key)
+			if err != nil {
+				return err
+			}
+			if err := p.expect(':'); err != nil {
+				return err
+			}
+			value, err := p.value(coll.Elem)
+			if err != nil {
+				return err
+			}
+
+			// blobs are not comparable, the driver marshals strings as blobs
+			if b, ok := key.([]byte); ok {
+				key = string(b)
+			} else if key != nil && !reflect.TypeOf(key).Comparable() {
+				return p.errorf("unsupported map key type %s", coll.Key)
+			}
+			m[key] = value
+			return nil
+		})
+		return m, err
+	case gocql.TypeTuple:
+		elems := info.(gocql.TupleTypeInfo).Elems
+		values := make([]interface{}, 0, len(elems))
+		err := p.sequence('(', ')', func(i int) error {
+			if i >= len(elems) {
+				return p.errorf("too many tuple elements, expected %d", len(elems))
+			}
+			v, err := p.value(elems[i])
+			values = append(values, v)
+			return err
+		})
+
+		// missing trailing elements are null
+		for len(values) < len(elems) {
+			values = append(values, nil)
+		}
+		return values, err
+	case gocql.TypeUDT:
+		udt, ok := info.(gocql.UDTTypeInfo)
+		if !ok {
+			break
+		}
+
+		fields := map[string]interface{}{}
+		err := p.sequence('{', '}', func(int) error {
+			name, quoted, err := p.token()
+			if err != nil {
+				return err
+			}
+			if !quoted {
+				name = strings.ToLower(name)
+			}
+
+			var typ gocql.TypeInfo
+			for _, field := range udt.Elements {
+				if field.Name == name {
+					typ = field.Type
+				}
+			}
+			if typ == nil {
+				return p.errorf("unknown field %q of %s", name, udt.Name)
+			}
+
+			if err := p.expect(':'); err != nil {
+				return err
+			}
+			fields[name], err = p.value(typ)
+			return err
+		})
+		return fields, err
+	}
+
+	s, _, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := p.f.parseScalar(info, s)
+	if err != nil {
+		return nil, p.errorf("%v", err)
+	}
+	return v, nil
+}
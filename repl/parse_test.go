@@ -0,0 +1,111 @@
+package repl
+
+import (
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/gocql/gocqlsh/config"
+
+	"github.com/logrusorgru/aurora"
+	"gopkg.in/inf.v0"
+)
+
+func TestParse(t *testing.T) {
+	ui := config.Default().UI
+	ui.TimeZone = time.UTC
+	f := newFormatter(ui, aurora.NewAurora(false))
+
+	text := native(gocql.TypeVarchar)
+	integer := native(gocql.TypeInt)
+	uuid, _ := gocql.ParseUUID("f2c993b9-6c2f-4137-a8f0-0fd5e5cc4433")
+	ts := time.Date(2017, 3, 4, 12, 30, 15, 123000000, time.UTC)
+
+	tuple := gocql.TupleTypeInfo{
+		NativeType: gocql.NewNativeType(4, gocql.TypeTuple, ""),
+		Elems:      []gocql.TypeInfo{integer, text, integer},
+	}
+	udt := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(4, gocql.TypeUDT, ""),
+		Name:       "address",
+		Elements: []gocql.UDTField{
+			{Name: "street", Type: text},
+			{Name: "zipCode", Type: integer},
+		},
+	}
+
+	tests := [...]struct {
+		info gocql.TypeInfo
+		in   string
+		exp  interface{}
+	}{
+		{text, " it's ", " it's "},
+		{integer, "-12", int64(-12)},
+		{native(gocql.TypeFloat), "1.5", float32(1.5)},
+		{native(gocql.TypeDouble), "-Infinity", math.Inf(-1)},
+		{native(gocql.TypeBoolean), "True", true},
+		{native(gocql.TypeDecimal), "1.25", *inf.NewDec(125, 2)},
+		{native(gocql.TypeVarint), "123456789012345678901234567890", func() *big.Int {
+			n, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+			return n
+		}()},
+		{native(gocql.TypeBlob), "0xcafe", []byte{0xca, 0xfe}},
+		{native(gocql.TypeUUID), uuid.String(), uuid},
+		{native(gocql.TypeInet), "127.0.0.1", net.ParseIP("127.0.0.1")},
+		{native(gocql.TypeTimestamp), f.time(ts), ts},
+		{native(gocql.TypeTimestamp), "2017-03-04T12:30:15.123Z", ts},
+		{native(gocql.TypeTimestamp), "1488630615123", ts.Local()},
+		{native(gocql.TypeDate), "2017-03-04", time.Date(2017, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{native(gocql.TypeTime), "12:30:15.5", 12*time.Hour + 30*time.Minute + 15*time.Second + 500*time.Millisecond},
+		{native(gocql.TypeDuration), "1y2mo3d4h5m6s7ms8us9ns", gocql.Duration{Months: 14, Days: 3,
+			Nanoseconds: int64(4*time.Hour + 5*time.Minute + 6*time.Second + 7*time.Millisecond + 8*time.Microsecond + 9)}},
+		{native(gocql.TypeDuration), "-2w", gocql.Duration{Days: -14}},
+		{collection(gocql.TypeList, nil, text), "['a', 'b''c', null]", []interface{}{"a", "b'c", nil}},
+		{collection(gocql.TypeSet, nil, integer), "{}", []interface{}{}},
+		{collection(gocql.TypeMap, text, collection(gocql.TypeList, nil, integer)), "{'a': [1, 2], 'b': []}",
+			map[interface{}]interface{}{"a": []interface{}{int64(1), int64(2)}, "b": []interface{}{}}},
+		{collection(gocql.TypeMap, native(gocql.TypeBlob), integer), "{0x01: 1}", map[interface{}]interface{}{"\x01": int64(1)}},
+		{tuple, "(1, 'a')", []interface{}{int64(1), "a", nil}},
+		{udt, `{street: 'Main St', "zipCode": 12345}`, map[string]interface{}{"street": "Main St", "zipCode": int64(12345)}},
+	}
+
+	for _, test := range tests {
+		v, err := f.parse(test.info, test.in)
+		if err != nil {
+			t.Errorf("%s as %s: %v", test.in, test.info, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.exp, v) {
+			t.Errorf("%s as %s: expected %#v got %#v", test.in, test.info, test.exp, v)
+		}
+
+		// values which can be parsed can be marshalled
+		if _, err := gocql.Marshal(test.info, v); err != nil {
+			t.Errorf("%s as %s: unable to marshal %#v: %v", test.in, test.info, v, err)
+		}
+	}
+
+	for _, test := range [...]struct {
+		info gocql.TypeInfo
+		in   string
+	}{
+		{integer, "1.5"},
+		{native(gocql.TypeTinyInt), "300"},
+		{native(gocql.TypeBlob), "cafe"},
+		{native(gocql.TypeTime), "25:00:00"},
+		{native(gocql.TypeDuration), "5x"},
+		{collection(gocql.TypeList, nil, integer), "[1, 2"},
+		{collection(gocql.TypeList, nil, integer), "[1] 2"},
+		{tuple, "(1, 'a', 2, 3)"},
+		{udt, "{city: 'x'}"},
+		{native(gocql.TypeCounter), "1"},
+	} {
+		if v, err := f.parse(test.info, test.in); err == nil {
+			t.Errorf("%s as %s: expected error got %#v", test.in, test.info, v)
+		}
+	}
+}
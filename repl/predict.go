@@ -4,14 +4,16 @@ import (
 	"bytes"
 	"log"
 
-	"github.com/gocql/gocql"
 	"github.com/gocql/gocqlsh/cql/lexer"
+	"github.com/gocql/gocqlsh/metadata"
 
 	"github.com/chzyer/readline"
 )
 
 type cqlCompleter struct {
-	db *gocql.Session
+	meta *metadata.Cassandra
+	// keyspace is the current keyspace which unqualified tables are in
+	keyspace string
 }
 
 func (c *cqlCompleter) Print(prefix string, level int, buf *bytes.Buffer) {
@@ -107,55 +109,47 @@ func (c *cqlCompleter) completeInsert(lex *lexer.Lexer) []string {
 	comp.Expect("into")
 	comp.Space()
 
-	// TODO: this could be table or keyspace, need to figure out
-	keyspace := comp.Accept(lexer.ItemIdentifier, func() []string {
-		// TODO: move this to gocql
-		var keyspaces []string
+	// a table in the current keyspace or a keyspace followed by a table
+	name := comp.Accept(lexer.ItemIdentifier, func() []string {
+		return append(c.keyspaces(), c.tables(c.keyspace)...)
+	})
 
-		s := c.db.Query("SELECT keyspace_name FROM system.schema_keyspaces").Iter().Scanner()
-		for s.Next() {
-			var name string
-			if err := s.Scan(&name); err != nil {
-				log.Println(err)
-				return nil
-			}
+	if name == "" {
+		return comp.items
+	}
 
-			keyspaces = append(keyspaces, name)
+	keyspace, table := c.keyspace, name
+	if !comp.Next() {
+		if c.hasTable(keyspace, name) {
+			comp.items = append(comp.items, "(")
+		} else {
+			comp.items = append(comp.items, ".")
 		}
+		return comp.items
+	}
 
-		if err := s.Err(); err != nil {
-			log.Println(err)
-		}
+	if comp.last.Typ == lexer.ItemDot {
+		keyspace = name
+		table = comp.Accept(lexer.ItemIdentifier, func() []string {
+			return c.tables(keyspace)
+		})
 
-		return keyspaces
-	})
+		if table == "" {
+			return comp.items
+		}
 
-	if keyspace == "" {
+		comp.Expect("(")
+	} else if comp.last.Val != "(" {
 		return comp.items
 	}
 
-	keyspaceMeta, err := c.db.KeyspaceMetadata(keyspace)
+	ks, err := c.meta.Keyspace(keyspace)
 	if err != nil {
 		// TODO: need to output errors somewhere
 		log.Println(err)
 		return comp.items
 	}
 
-	comp.Expect(".")
-	table := comp.Accept(lexer.ItemIdentifier, func() []string {
-		var tables []string
-		for table := range keyspaceMeta.Tables {
-			tables = append(tables, table)
-		}
-		return tables
-	})
-
-	if table == "" {
-		return comp.items
-	}
-
-	comp.Expect("(")
-
 	var columns []string
 
 	// column list
@@ -165,12 +159,16 @@ func (c *cqlCompleter) completeInsert(lex *lexer.Lexer) []string {
 		case lexer.ItemComma:
 			comp.Space()
 			col := comp.Accept(lexer.ItemIdentifier, func() []string {
-				m, ok := keyspaceMeta.Tables[table]
-				if !ok {
+				t := ks.Table(table)
+				if t == nil {
 					return nil
 				}
 
-				return m.OrderedColumns
+				names := make([]string, len(t.Columns))
+				for i, col := range t.Columns {
+					names[i] = col.Name
+				}
+				return names
 			})
 
 			columns = append(columns, col)
@@ -186,6 +184,48 @@ func (c *cqlCompleter) completeInsert(lex *lexer.Lexer) []string {
 	return comp.items
 }
 
+func (c *cqlCompleter) keyspaces() []string {
+	keyspaces, err := c.meta.Keyspaces()
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	names := make([]string, len(keyspaces))
+	for i, ks := range keyspaces {
+		names[i] = ks.Name
+	}
+	return names
+}
+
+// tables returns the names of the tables in keyspace.
+func (c *cqlCompleter) tables(keyspace string) []string {
+	if keyspace == "" {
+		return nil
+	}
+
+	ks, err := c.meta.Keyspace(keyspace)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	tables := make([]string, len(ks.Tables))
+	for i, t := range ks.Tables {
+		tables[i] = t.Name
+	}
+	return tables
+}
+
+func (c *cqlCompleter) hasTable(keyspace, table string) bool {
+	for _, name := range c.tables(keyspace) {
+		if name == table {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *cqlCompleter) queryParser(q string) []string {
 	l := lexer.Lex(q)
 	keyword := l.ItemNoWS()
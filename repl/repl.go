@@ -2,8 +2,14 @@ package repl
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
 
 	"github.com/gocql/gocql"
+	"github.com/gocql/gocqlsh/config"
+	"github.com/gocql/gocqlsh/cql/lexer"
 	"github.com/gocql/gocqlsh/metadata"
 
 	"github.com/chzyer/readline"
@@ -11,26 +17,125 @@ import (
 	"github.com/olekukonko/tablewriter"
 )
 
+const (
+	prompt         = "gocqlsh> "
+	continuePrompt = "   ... "
+)
+
 type CQL struct {
-	db   *gocql.Session
-	r    *readline.Instance
-	meta *metadata.Cassandra
+	// cluster is the config db was created from, it is used to reconnect
+	// when the session needs to change, ie on LOGIN.
+	cluster *gocql.ClusterConfig
+
+	db        *gocql.Session
+	meta      *metadata.Cassandra
+	completer *cqlCompleter
+	commands  *dispatcher
+
+	// r is nil when not running interactively
+	r *readline.Instance
+
+	// out and errOut are where output and errors are written, they write to
+	// stdout and stderr and to the capture file when output is captured.
+	out     io.Writer
+	errOut  io.Writer
+	stdout  io.Writer
+	stderr  io.Writer
+	capture *capture
+
+	consistency       gocql.Consistency
+	serialConsistency gocql.SerialConsistency
+
+	// pageSize is the number of rows shown at a time, 0 disables paging
+	pageSize int
+	tracing  bool
+	expand   expandMode
+	output   outputFormat
+
+	// history is the most recent statements executed, executed is the
+	// total number executed.
+	history  []historyEntry
+	executed int
+
+	au     aurora.Aurora
+	format *formatter
 }
 
-func New(db *gocql.Session, r *readline.Instance) *CQL {
+func newCQL(cfg *config.Config, cluster *gocql.ClusterConfig, db *gocql.Session) *CQL {
+	au := aurora.NewAurora(cfg.UI.Color)
+	meta := newMetadata(cluster, db)
+	c := &CQL{
+		cluster:   cluster,
+		db:        db,
+		meta:      meta,
+		completer: &cqlCompleter{meta: meta, keyspace: cluster.Keyspace},
+		au:        au,
+		format:    newFormatter(cfg.UI, au),
+	}
+	c.consistency = cluster.Consistency
+	c.serialConsistency = cluster.SerialConsistency
+	if c.serialConsistency == 0 {
+		c.serialConsistency = gocql.Serial
+	}
+	c.pageSize = defaultPageSize
+	c.output = formatTable
+	if format, err := parseOutputFormat(cfg.UI.Format); err == nil {
+		c.output = format
+	}
+	c.commands = newDispatcher(c.executeQuery)
+	c.registerCommands()
+	return c
+}
+
+func New(cfg *config.Config, cluster *gocql.ClusterConfig, db *gocql.Session, r *readline.Instance) *CQL {
 	// TODO: protbably want to pass this in for testing
-	r.Config.AutoComplete = &cqlCompleter{db}
+	c := newCQL(cfg, cluster, db)
+	r.Config.AutoComplete = c.completer
+	c.r = r
+	c.setOutput(r, r)
+	return c
+}
+
+// NewExec returns a CQL for running statements non-interactively with Execute,
+// results are written to out and errors to errOut.
+func NewExec(cfg *config.Config, cluster *gocql.ClusterConfig, db *gocql.Session, out, errOut io.Writer) *CQL {
+	c := newCQL(cfg, cluster, db)
+	c.setOutput(out, errOut)
+	return c
+}
+
+// reconnect creates a new session from cluster and replaces the current
+// session with it, the old session is closed only once the new one is
+// established so that a failed reconnect leaves the shell usable.
+func (c *CQL) reconnect(cluster *gocql.ClusterConfig) error {
+	db, err := cluster.CreateSession()
+	if err != nil {
+		return err
+	}
+
+	c.db.Close()
+
+	c.cluster = cluster
+	c.db = db
+	c.meta = newMetadata(cluster, db)
+	c.completer.meta = c.meta
+	c.completer.keyspace = cluster.Keyspace
+	return nil
+}
+
+// newMetadata returns the metadata for db, its cached schema is invalidated
+// by the schema events observed by cluster if it observes them.
+func newMetadata(cluster *gocql.ClusterConfig, db *gocql.Session) *metadata.Cassandra {
 	meta := metadata.New(db)
-	return &CQL{
-		db:   db,
-		r:    r,
-		meta: meta,
+	if events, ok := cluster.FrameHeaderObserver.(*metadata.SchemaEvents); ok {
+		meta.Watch(events)
 	}
+	return meta
 }
 
 func (c *CQL) err(err error) {
 	// TODO: improve error display
-	if _, err := fmt.Fprintf(c.r, "error: %v\n", aurora.Red(err)); err != nil {
+	if _, err := fmt.Fprintf(c.errOut, "error: %v\n", c.au.Red(err)); err != nil {
 		panic(err)
 	}
 }
@@ -41,64 +146,301 @@ func (c *CQL) Run() error {
 		return err
 	}
 
-	if _, err := fmt.Fprintf(c.r, "Connected to %s at %v\n", aurora.Magenta(clusterInfo.Name), clusterInfo.Address); err != nil {
+	if _, err := fmt.Fprintf(c.out, "Connected to %s at %v\n", c.au.Magenta(clusterInfo.Name), clusterInfo.Address); err != nil {
 		return err
 	}
 
-	if _, err := fmt.Fprintf(c.r, "[gocqlsh | Cassandra %s | CQL Spec %s | Native Protocol %s]\n", clusterInfo.Version,
-		clusterInfo.CQLVersion, clusterInfo.Protocol); err != nil {
+	transport := "Unencrypted"
+	if c.cluster.SslOpts != nil {
+		transport = c.au.Green("TLS").String()
+	}
+
+	if _, err := fmt.Fprintf(c.out, "[gocqlsh | Cassandra %s | CQL Spec %s | Native Protocol %s | %s]\n", clusterInfo.Version,
+		clusterInfo.CQLVersion, clusterInfo.Protocol, transport); err != nil {
 		return err
 	}
 
+	// buf holds the lines of a statement which has not yet been terminated
+	var buf strings.Builder
 	for {
+		if buf.Len() == 0 {
+			c.r.SetPrompt(c.prompt())
+		} else {
+			c.r.SetPrompt(continuePrompt)
+		}
+
 		line, err := c.r.Readline()
-		if err != nil {
+		if err == readline.ErrInterrupt {
+			// discard any partially entered statement
+			buf.Reset()
+			continue
+		} else if err != nil {
 			return err
 		}
 
-		if err := c.exec(line); err != nil {
-			c.err(err)
+		if buf.Len() == 0 && strings.TrimSpace(line) == "" {
+			continue
 		}
 
+		// like cqlsh shell commands entered on a single line do not need to
+		// be terminated by a semicolon
+		_, _, isCommand := c.commands.command(line)
+		isCommand = isCommand && buf.Len() == 0
+
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+		if !isCommand && !statementComplete(buf.String()) {
+			continue
+		}
+
+		input := buf.String()
+		buf.Reset()
+
+		// the history file has an entry per line, statements which can not
+		// be joined into one are not saved
+		if line, ok := oneLine(input); ok && !c.sensitive(input) {
+			c.r.SaveHistory(line)
+		}
+
+		if err := c.execStatements("", input, true); err == errExit {
+			return io.EOF
+		}
+	}
+}
+
+// Execute runs each statement in input in order, reporting errors as they
+// occur. Unless continueOnError is set execution stops at the first statement
+// which fails. The first error encountered is returned.
+func (c *CQL) Execute(input string, continueOnError bool) error {
+	err := c.execStatements("", input, continueOnError)
+	if err == errExit {
+		return nil
+	} else if err != nil && !continueOnError {
+		c.err(err)
+	}
+	return err
+}
+
+// ExecuteFile runs the statements in the file at path as Execute does, errors
+// are reported with the line number of the statement which failed.
+func (c *CQL) ExecuteFile(path string, continueOnError bool) error {
+	input, err := ioutil.ReadFile(path)
+	if err != nil {
+		c.err(err)
+		return err
 	}
+
+	err = c.execStatements(path, string(input), continueOnError)
+	if err == errExit {
+		return nil
+	} else if err != nil && !continueOnError {
+		c.err(err)
+	}
+	return err
+}
+
+// execStatements executes the statements in input in order, name is used to
+// prefix errors with the location of the failing statement. When
+// continueOnError is set errors are reported as they occur and the first is
+// returned, otherwise the first error is returned without being reported.
+// Execution always stops if a statement exits the shell.
+func (c *CQL) execStatements(name, input string, continueOnError bool) error {
+	var firstErr error
+	for _, stmt := range splitStatements(input) {
+		started := time.Now()
+		err := c.exec(stmt.cql)
+		c.record(stmt.cql, started, err)
+		if err == nil {
+			continue
+		} else if err == errExit {
+			return err
+		}
+
+		if name != "" {
+			err = fmt.Errorf("%s:%d: %v", name, stmt.line, err)
+		}
+		if !continueOnError {
+			return err
+		}
+
+		c.err(err)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
 }
 
 func (c *CQL) executeQuery(query string) error {
-	iter := c.db.Query(query).Iter()
+	if keyspace, ok := c.schemaKeyspace(query); ok {
+		// a statement which fails may still have changed the schema, ie if
+		// it timed out waiting for schema agreement
+		defer c.meta.Invalidate(keyspace)
+	}
 
-	table := tablewriter.NewWriter(c.r)
-	table.SetAutoFormatHeaders(false)
-	var header, columns []string
-	for _, col := range iter.Columns() {
-		header = append(header, aurora.Red(col.Name).String())
-		columns = append(columns, col.Name)
+	q := c.db.Query(query).Consistency(c.consistency).SerialConsistency(c.serialConsistency)
+	if !c.tracing {
+		return c.showResults(q)
 	}
 
-	table.SetHeader(header)
-	table.SetAlignment(tablewriter.ALIGN_RIGHT)
+	tracer := &traceIDs{}
+	q.Trace(tracer)
 
-	rows, err := iter.SliceMap()
-	if err != nil {
-		// TODO: write errors to repl
-		return err
+	err := c.showResults(q)
+	if traceErr := c.showTraces(tracer); err == nil {
+		err = traceErr
+	}
+	return err
+}
+
+// showResults executes q and renders its results, a page at a time if paging
+// is enabled and results are shown as a table.
+func (c *CQL) showResults(q *gocql.Query) error {
+	if c.pageSize == 0 || c.r == nil || c.output != formatTable {
+		iter := q.Iter()
+		if _, err := c.renderRows(iter, 1); err != nil {
+			iter.Close()
+			return err
+		}
+		return iter.Close()
+	}
+
+	// fetch and render a page at a time, resuming from the previous page
+	// state, until either the results are exhausted or the user stops
+	q.PageSize(c.pageSize)
+	var state []byte
+	row := 1
+	for {
+		iter := q.PageState(state).Iter()
+		n, err := c.renderRows(iter, row)
+		if err != nil {
+			iter.Close()
+			return err
+		}
+		row += n
+
+		state = iter.PageState()
+		if err := iter.Close(); err != nil {
+			return err
+		} else if len(state) == 0 {
+			return nil
+		}
+
+		if more, err := c.more(); err != nil || !more {
+			return err
+		}
+	}
+}
+
+// renderRows writes the rows remaining in iter as a table, or vertically if
+// expanded output is enabled, unless another output format is in use,
+// returning the number of rows written. Rows are numbered from first when
+// shown vertically.
+func (c *CQL) renderRows(iter *gocql.Iter, first int) (int, error) {
+	if c.output != formatTable {
+		return c.writeRows(iter)
 	}
 
-	line := make([]string, len(columns))
-	for _, row := range rows {
+	columns := iter.Columns()
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = c.au.Red(col.Name).String()
+	}
+
+	var lines [][]string
+	rows := newRowReader(columns)
+	for rows.next(iter) {
+		line := make([]string, len(columns))
 		for i, col := range columns {
-			line[i] = fmt.Sprintf("%v", row[col])
+			line[i] = c.format.format(col.TypeInfo, rows.row[i])
 		}
-		table.Append(line)
+		lines = append(lines, line)
+	}
+	if rows.err != nil {
+		return 0, rows.err
 	}
 
+	if c.expanded(header, lines) {
+		return len(lines), renderExpanded(c.out, header, lines, first)
+	}
+
+	table := tablewriter.NewWriter(c.out)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader(header)
+	table.SetAlignment(tablewriter.ALIGN_RIGHT)
+	table.AppendBulk(lines)
 	table.Render()
+	return len(lines), nil
+}
+
+// login handles LOGIN <username> [<password>], prompting for the password
+// if it is not given.
+func (c *CQL) login(_ string, l *lexer.Lexer) error {
+	username := unquote(l.ItemNoWS())
+	if username == "" {
+		return fmt.Errorf("usage: LOGIN <username> [<password>]")
+	}
 
-	// TODO: store page state and query here so that we can let the user page through results with space
+	password := unquote(l.ItemNoWS())
+	if password == "" {
+		if c.r == nil {
+			return fmt.Errorf("a password is required to LOGIN when not running interactively")
+		}
+
+		b, err := c.r.ReadPassword("Password: ")
+		if err != nil {
+			return err
+		}
+		password = string(b)
+	}
+
+	cluster := *c.cluster
+	cluster.Authenticator = gocql.PasswordAuthenticator{
+		Username: username,
+		Password: password,
+	}
+
+	return c.reconnect(&cluster)
+}
+
+// source handles SOURCE '<file>', executing the statements in file and
+// stopping at the first which fails.
+func (c *CQL) source(_ string, l *lexer.Lexer) error {
+	path := l.ItemNoWS()
+	if path.Typ != lexer.ItemString {
+		return fmt.Errorf("usage: SOURCE '<file>'")
+	}
+
+	name := config.ExpandHome(unquote(path))
+	input, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	return c.execStatements(name, string(input), false)
+}
+
+// unquote returns the value of an identifier or string item with any
+// surrounding quotes removed, items of any other type return an empty string.
+func unquote(item lexer.Item) string {
+	switch item.Typ {
+	case lexer.ItemIdentifier, lexer.ItemKeyword:
+		if len(item.Val) >= 2 && item.Val[0] == '"' {
+			return strings.Replace(item.Val[1:len(item.Val)-1], `""`, `"`, -1)
+		}
+		return item.Val
+	case lexer.ItemString:
+		if len(item.Val) >= 4 && strings.HasPrefix(item.Val, "$$") {
+			return item.Val[2 : len(item.Val)-2]
+		} else if len(item.Val) >= 2 {
+			return strings.Replace(item.Val[1:len(item.Val)-1], "''", "'", -1)
+		}
+	}
 
-	return iter.Close()
+	return ""
 }
 
 func (c *CQL) exec(line string) error {
-	// TODO: parse and do other things
-	return c.executeQuery(line)
+	return c.commands.dispatch(line)
 }
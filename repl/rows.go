@@ -0,0 +1,313 @@
+package repl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+
+	"github.com/gocql/gocql"
+)
+
+// rawColumn is a gocql.Unmarshaler which keeps the bytes of a column so that
+// they can be decoded once the row has been read.
+type rawColumn struct {
+	data []byte
+	null bool
+}
+
+func (r *rawColumn) UnmarshalCQL(info gocql.TypeInfo, data []byte) error {
+	// data is only valid until the next row is read
+	r.null = data == nil
+	r.data = append(r.data[:0], data...)
+	return nil
+}
+
+// bytes returns the data of the column, which is nil if it is null and
+// non-nil but empty if it is an empty value.
+func (r *rawColumn) bytes() []byte {
+	if r.null {
+		return nil
+	} else if r.data == nil {
+		return []byte{}
+	}
+	return r.data
+}
+
+// rowReader scans rows from an iterator by position, columns which are null
+// are returned as nil. The values are reused between calls to next.
+type rowReader struct {
+	columns []gocql.ColumnInfo
+	// raw holds a value per column except for tuples which the driver
+	// splits into a value per element.
+	raw  []rawColumn
+	dest []interface{}
+	row  []interface{}
+	err  error
+}
+
+func newRowReader(columns []gocql.ColumnInfo) *rowReader {
+	r := &rowReader{
+		columns: columns,
+		row:     make([]interface{}, len(columns)),
+	}
+
+	n := 0
+	for _, col := range columns {
+		n += columnWidth(col)
+	}
+
+	r.raw = make([]rawColumn, n)
+	r.dest = make([]interface{}, n)
+	for i := range r.raw {
+		r.dest[i] = &r.raw[i]
+	}
+
+	return r
+}
+
+// columnWidth returns the number of values the driver scans col into.
+func columnWidth(col gocql.ColumnInfo) int {
+	if tuple, ok := col.TypeInfo.(gocql.TupleTypeInfo); ok {
+		return len(tuple.Elems)
+	}
+	return 1
+}
+
+// next reads the next row from iter, returning false once there are no more
+// rows or the row could not be decoded in which case err is set.
+func (r *rowReader) next(iter *gocql.Iter) bool {
+	if r.err != nil || !iter.Scan(r.dest...) {
+		return false
+	}
+
+	r.err = r.decodeRow()
+	return r.err == nil
+}
+
+func (r *rowReader) decodeRow() error {
+	raw := r.raw
+	for i, col := range r.columns {
+		tuple, ok := col.TypeInfo.(gocql.TupleTypeInfo)
+		if !ok {
+			v, err := decode(col.TypeInfo, raw[0].bytes())
+			if err != nil {
+				return fmt.Errorf("unable to decode column %s: %v", col.Name, err)
+			}
+			r.row[i] = v
+			raw = raw[1:]
+			continue
+		}
+
+		elems := make([]interface{}, len(tuple.Elems))
+		for j, elem := range tuple.Elems {
+			v, err := decode(elem, raw[j].bytes())
+			if err != nil {
+				return fmt.Errorf("unable to decode column %s: %v", col.Name, err)
+			}
+			elems[j] = v
+		}
+		r.row[i] = elems
+		raw = raw[len(tuple.Elems):]
+	}
+
+	return nil
+}
+
+// mapEntry is a key value pair of a decoded map, maps are decoded as a slice
+// of entries to keep the order they were returned in.
+type mapEntry struct {
+	key, value interface{}
+}
+
+// decode unmarshals data as type info, nil data is null and returns nil.
+// Lists and sets are decoded into []interface{}, maps into []mapEntry and
+// tuples and UDTs into a []interface{} of their elements so that the order and
+// any null elements are preserved.
+func decode(info gocql.TypeInfo, data []byte) (interface{}, error) {
+	if data == nil {
+		return nil, nil
+	} else if len(data) == 0 {
+		return empty(info), nil
+	}
+
+	switch info.Type() {
+	case gocql.TypeList, gocql.TypeSet:
+		coll := info.(gocql.CollectionType)
+		d := collectionDecoder{proto: coll.Version(), data: data}
+		items := make([]interface{}, d.len())
+		for i := range items {
+			elem := d.next()
+			if d.err != nil {
+				break
+			}
+
+			v, err := decode(coll.Elem, elem)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = v
+		}
+		return items, d.err
+	case gocql.TypeMap:
+		coll := info.(gocql.CollectionType)
+		d := collectionDecoder{proto: coll.Version(), data: data}
+		entries := make([]mapEntry, d.len())
+		for i := range entries {
+			key, value := d.next(), d.next()
+			if d.err != nil {
+				break
+			}
+
+			var err error
+			if entries[i].key, err = decode(coll.Key, key); err != nil {
+				return nil, err
+			}
+			if entries[i].value, err = decode(coll.Elem, value); err != nil {
+				return nil, err
+			}
+		}
+		return entries, d.err
+	case gocql.TypeTuple:
+		return decodeFields(info.(gocql.TupleTypeInfo).Elems, data)
+	case gocql.TypeUDT:
+		fields := info.(gocql.UDTTypeInfo).Elements
+		types := make([]gocql.TypeInfo, len(fields))
+		for i, field := range fields {
+			types[i] = field.Type
+		}
+		return decodeFields(types, data)
+	}
+
+	v, err := info.NewWithError()
+	if err != nil {
+		// no Go type for this CQL type, ie a custom type, keep the bytes
+		return data, nil
+	}
+
+	if err := gocql.Unmarshal(info, data, v); err != nil {
+		return nil, err
+	}
+
+	return reflect.ValueOf(v).Elem().Interface(), nil
+}
+
+// emptyValue is a value which is not null but has no bytes, which every type
+// allows. It is shown as nothing rather than as the zero value of its type.
+type emptyValue struct{}
+
+// empty returns the value of type info which has no bytes.
+func empty(info gocql.TypeInfo) interface{} {
+	switch info.Type() {
+	case gocql.TypeAscii, gocql.TypeText, gocql.TypeVarchar:
+		return ""
+	case gocql.TypeBlob, gocql.TypeCustom:
+		return []byte{}
+	case gocql.TypeList, gocql.TypeSet:
+		return []interface{}{}
+	case gocql.TypeMap:
+		return []mapEntry{}
+	}
+	return emptyValue{}
+}
+
+// decodeFields decodes the elements of a tuple or UDT, each of which is
+// prefixed with its length. Trailing elements which are missing, ie fields
+// added to a UDT after the value was written, are null.
+func decodeFields(types []gocql.TypeInfo, data []byte) ([]interface{}, error) {
+	values := make([]interface{}, len(types))
+	for i, typ := range types {
+		if len(data) < 4 {
+			break
+		}
+
+		var field []byte
+		field, data = readBytes(data, 4)
+		if field == nil && data == nil {
+			return nil, fmt.Errorf("truncated value for %s", typ)
+		}
+
+		v, err := decode(typ, field)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+// collectionDecoder reads the length prefixed elements of a collection which
+// are prefixed by 2 byte lengths before protocol version 3 and 4 after.
+type collectionDecoder struct {
+	proto byte
+	data  []byte
+	err   error
+}
+
+func (d *collectionDecoder) size() int {
+	if d.proto > 2 {
+		return 4
+	}
+	return 2
+}
+
+func (d *collectionDecoder) len() int {
+	n := d.size()
+	if len(d.data) < n {
+		d.err = fmt.Errorf("truncated collection")
+		return 0
+	}
+
+	var l int
+	if n == 4 {
+		l = int(int32(binary.BigEndian.Uint32(d.data)))
+	} else {
+		l = int(binary.BigEndian.Uint16(d.data))
+	}
+	d.data = d.data[n:]
+
+	if l < 0 {
+		d.err = fmt.Errorf("invalid collection length %d", l)
+		return 0
+	}
+	return l
+}
+
+func (d *collectionDecoder) next() []byte {
+	if d.err != nil {
+		return nil
+	}
+
+	elem, rest := readBytes(d.data, d.size())
+	if elem == nil && rest == nil {
+		d.err = fmt.Errorf("truncated collection")
+		return nil
+	}
+
+	d.data = rest
+	return elem
+}
+
+// readBytes reads a value prefixed with an n byte length from data, a negative
+// length is a null value. If data is too short both returned slices are nil.
+func readBytes(data []byte, n int) (value, rest []byte) {
+	if len(data) < n {
+		return nil, nil
+	}
+
+	var l int
+	if n == 4 {
+		l = int(int32(binary.BigEndian.Uint32(data)))
+	} else {
+		l = int(binary.BigEndian.Uint16(data))
+	}
+	data = data[n:]
+
+	if l < 0 {
+		return nil, data
+	} else if len(data) < l {
+		return nil, nil
+	}
+
+	return data[:l:l], data[l:]
+}
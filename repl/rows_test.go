@@ -0,0 +1,190 @@
+package repl
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/gocql/gocqlsh/config"
+
+	"github.com/logrusorgru/aurora"
+)
+
+func marshal(t *testing.T, info gocql.TypeInfo, v interface{}) []byte {
+	t.Helper()
+
+	b, err := gocql.Marshal(info, v)
+	if err != nil {
+		t.Fatalf("unable to marshal %v as %v: %v", v, info, err)
+	}
+	return b
+}
+
+// bytesValue returns b prefixed with its 4 byte length, nil is written as null.
+func bytesValue(b []byte) []byte {
+	n := int32(len(b))
+	if b == nil {
+		n = -1
+	}
+
+	out := make([]byte, 4, 4+len(b))
+	binary.BigEndian.PutUint32(out, uint32(n))
+	return append(out, b...)
+}
+
+func TestDecode(t *testing.T) {
+	text := native(gocql.TypeVarchar)
+	integer := native(gocql.TypeInt)
+	tuple := gocql.TupleTypeInfo{
+		NativeType: gocql.NewNativeType(4, gocql.TypeTuple, ""),
+		Elems:      []gocql.TypeInfo{integer, text},
+	}
+	udt := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(4, gocql.TypeUDT, ""),
+		Name:       "address",
+		Elements: []gocql.UDTField{
+			{Name: "street", Type: text},
+			{Name: "number", Type: integer},
+			{Name: "flat", Type: integer},
+		},
+	}
+
+	// maps are written by hand as the driver marshals Go maps in random order
+	mapData := []byte{0, 0, 0, 2}
+	for _, kv := range [...][2]interface{}{{"b", 2}, {"a", nil}} {
+		mapData = append(mapData, bytesValue([]byte(kv[0].(string)))...)
+		if kv[1] == nil {
+			mapData = append(mapData, bytesValue(nil)...)
+		} else {
+			mapData = append(mapData, bytesValue(marshal(t, integer, kv[1]))...)
+		}
+	}
+
+	// a UDT written before the flat field was added
+	udtData := append(bytesValue([]byte("Main St")), bytesValue(nil)...)
+
+	tests := [...]struct {
+		name string
+		info gocql.TypeInfo
+		data []byte
+		exp  interface{}
+	}{
+		{"null", integer, nil, nil},
+		{"zero", integer, marshal(t, integer, 0), 0},
+		{"empty text", text, []byte{}, ""},
+		{"list", collection(gocql.TypeList, nil, integer), marshal(t, collection(gocql.TypeList, nil, integer), []int{3, 1, 2}),
+			[]interface{}{3, 1, 2}},
+		{"map", collection(gocql.TypeMap, text, integer), mapData,
+			[]mapEntry{{"b", 2}, {"a", nil}}},
+		{"tuple", tuple, append(bytesValue(nil), bytesValue([]byte("x"))...), []interface{}{nil, "x"}},
+		{"udt", udt, udtData, []interface{}{"Main St", nil, nil}},
+		{"custom", gocql.NewNativeType(4, gocql.TypeCustom, "org.example.Type"), []byte{1, 2}, []byte{1, 2}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := decode(test.info, test.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(test.exp, v) {
+				t.Fatalf("expected %#v got %#v", test.exp, v)
+			}
+		})
+	}
+
+	if _, err := decode(collection(gocql.TypeList, nil, integer), []byte{0, 0, 0, 2, 0, 0, 0, 4}); err == nil {
+		t.Fatal("expected an error decoding a truncated list")
+	}
+}
+
+func TestRowReader(t *testing.T) {
+	integer := native(gocql.TypeInt)
+	text := native(gocql.TypeVarchar)
+	columns := []gocql.ColumnInfo{
+		{Name: "a", TypeInfo: integer},
+		{Name: "t", TypeInfo: gocql.TupleTypeInfo{
+			NativeType: gocql.NewNativeType(4, gocql.TypeTuple, ""),
+			Elems:      []gocql.TypeInfo{integer, text},
+		}},
+		{Name: "a", TypeInfo: integer},
+	}
+
+	r := newRowReader(columns)
+	if len(r.dest) != 4 {
+		t.Fatalf("expected tuple to be scanned into 2 values, got %d destinations", len(r.dest))
+	}
+
+	values := [][]byte{marshal(t, integer, 0), marshal(t, integer, 1), []byte("x"), nil}
+	for i, data := range values {
+		if err := r.raw[i].UnmarshalCQL(nil, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := r.decodeRow(); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []interface{}{0, []interface{}{1, "x"}, nil}
+	if !reflect.DeepEqual(exp, r.row) {
+		t.Fatalf("expected %#v got %#v", exp, r.row)
+	}
+
+	f := newFormatter(config.Default().UI, aurora.NewAurora(false))
+	var cells []string
+	for i, col := range columns {
+		cells = append(cells, f.format(col.TypeInfo, r.row[i]))
+	}
+	if exp := []string{"0", "(1, 'x')", "null"}; !reflect.DeepEqual(exp, cells) {
+		t.Fatalf("expected %q got %q", exp, cells)
+	}
+}
+
+func TestRowReaderEmpty(t *testing.T) {
+	columns := []gocql.ColumnInfo{
+		{Name: "t", TypeInfo: native(gocql.TypeVarchar)},
+		{Name: "b", TypeInfo: native(gocql.TypeBlob)},
+		{Name: "l", TypeInfo: collection(gocql.TypeList, nil, native(gocql.TypeInt))},
+		{Name: "m", TypeInfo: collection(gocql.TypeMap, native(gocql.TypeInt), native(gocql.TypeInt))},
+		{Name: "i", TypeInfo: native(gocql.TypeInt)},
+		{Name: "ts", TypeInfo: native(gocql.TypeTimestamp)},
+	}
+
+	f := newFormatter(config.Default().UI, aurora.NewAurora(false))
+	tests := [...]struct {
+		data  []byte
+		exp   []interface{}
+		cells []string
+	}{
+		// empty values are not null
+		{[]byte{}, []interface{}{"", []byte{}, []interface{}{}, []mapEntry{}, emptyValue{}, emptyValue{}},
+			[]string{"", "0x", "[]", "{}", "", ""}},
+		{nil, []interface{}{nil, nil, nil, nil, nil, nil}, []string{"null", "null", "null", "null", "null", "null"}},
+	}
+
+	for _, test := range tests {
+		r := newRowReader(columns)
+		for i := range r.raw {
+			if err := r.raw[i].UnmarshalCQL(nil, test.data); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := r.decodeRow(); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(test.exp, r.row) {
+			t.Errorf("%#v: expected %#v got %#v", test.data, test.exp, r.row)
+		}
+
+		var cells []string
+		for i, col := range columns {
+			cells = append(cells, f.format(col.TypeInfo, r.row[i]))
+		}
+		if !reflect.DeepEqual(test.cells, cells) {
+			t.Errorf("%#v: expected %q got %q", test.data, test.cells, cells)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package repl
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gocql/gocqlsh/cql/lexer"
+)
+
+// schemaObjects are what schema statements create, alter or drop, and
+// schemaWords the words which may come before the object or its name.
+var (
+	schemaObjects = map[string]bool{
+		"keyspace": true, "schema": true, "table": true, "columnfamily": true, "type": true,
+		"function": true, "aggregate": true, "index": true, "view": true, "trigger": true,
+	}
+	schemaWords = map[string]bool{
+		"or": true, "replace": true, "custom": true, "materialized": true, "if": true, "not": true, "exists": true,
+	}
+)
+
+// schemaKeyspace reports whether stmt changes the schema and if so the
+// keyspace it changes, which is empty if it is unknown.
+func (c *CQL) schemaKeyspace(stmt string) (string, bool) {
+	var items []lexer.Item
+	for l := lexer.Lex(stmt); ; {
+		item := l.ItemNoWS()
+		if item.Typ == lexer.ItemEOF || item.Typ == lexer.ItemSemiColon || item.Typ == lexer.ItemError {
+			break
+		}
+		items = append(items, item)
+	}
+
+	word := func(i int) string {
+		if i < len(items) {
+			return strings.ToLower(items[i].Val)
+		}
+		return ""
+	}
+
+	switch word(0) {
+	case "create", "alter", "drop":
+	default:
+		return "", false
+	}
+
+	i := 1
+	for schemaWords[word(i)] {
+		i++
+	}
+	object := word(i)
+	if !schemaObjects[object] {
+		return "", false
+	}
+	for i++; schemaWords[word(i)]; i++ {
+	}
+
+	switch object {
+	case "keyspace", "schema":
+		if i < len(items) && isIdentifier(items[i]) {
+			return identifier(items[i]), true
+		}
+		return "", true
+	case "index", "trigger":
+		// indexes and triggers are created on a table, triggers are also
+		// dropped from one
+		for j := i; j < len(items); j++ {
+			if word(j) == "on" {
+				i = j + 1
+				break
+			}
+		}
+	}
+
+	if i+1 < len(items) && isIdentifier(items[i]) && items[i+1].Typ == lexer.ItemDot {
+		return identifier(items[i]), true
+	}
+	return c.keyspace(), true
+}
+
+// refreshCommand handles REFRESH SCHEMA, which discards the cached schema and
+// reads it again from the cluster.
+func (c *CQL) refreshCommand(_ string, args *lexer.Lexer) error {
+	usage := errors.New("usage: REFRESH SCHEMA")
+	if item := args.ItemNoWS(); !strings.EqualFold(item.Val, "schema") {
+		return usage
+	}
+	if end := args.ItemNoWS(); end.Typ != lexer.ItemEOF && end.Typ != lexer.ItemSemiColon {
+		return usage
+	}
+
+	c.meta.Invalidate("")
+	_, err := c.meta.Keyspaces()
+	return err
+}
@@ -0,0 +1,47 @@
+package repl
+
+import "testing"
+
+func TestSchemaKeyspace(t *testing.T) {
+	c, _ := newTestCQL()
+	c.cluster.Keyspace = "events"
+
+	tests := [...]struct {
+		stmt     string
+		keyspace string
+		schema   bool
+	}{
+		{"SELECT * FROM shop.orders", "", false},
+		{"INSERT INTO orders (id) VALUES (1)", "", false},
+		{"CREATE ROLE admin", "", false},
+		{"ALTER USER admin WITH PASSWORD 'x'", "", false},
+		{"CREATE KEYSPACE IF NOT EXISTS Shop WITH replication = {'class': 'SimpleStrategy'}", "shop", true},
+		{`DROP SCHEMA "Shop";`, "Shop", true},
+		{"CREATE TABLE shop.orders (id int PRIMARY KEY)", "shop", true},
+		{"create table orders (id int primary key, address frozen<shop.address>)", "events", true},
+		{"ALTER COLUMNFAMILY orders ADD total int", "events", true},
+		{"DROP TYPE IF EXISTS shop.address", "shop", true},
+		{"CREATE OR REPLACE FUNCTION shop.twice (a int) RETURNS NULL ON NULL INPUT RETURNS int LANGUAGE java AS 'return a * 2;'", "shop", true},
+		{"CREATE INDEX orders_total ON shop.orders (total)", "shop", true},
+		{"CREATE CUSTOM INDEX ON orders (total) USING 'Index'", "events", true},
+		{"DROP INDEX shop.orders_total", "shop", true},
+		{"CREATE MATERIALIZED VIEW IF NOT EXISTS shop.by_total AS SELECT * FROM shop.orders", "shop", true},
+		{"DROP TRIGGER audit ON shop.orders", "shop", true},
+	}
+
+	for _, test := range tests {
+		keyspace, schema := c.schemaKeyspace(test.stmt)
+		if keyspace != test.keyspace || schema != test.schema {
+			t.Errorf("%s: expected %q %v got %q %v", test.stmt, test.keyspace, test.schema, keyspace, schema)
+		}
+	}
+}
+
+func TestRefreshUsage(t *testing.T) {
+	c, _ := newTestCQL()
+	for _, stmt := range []string{"REFRESH", "REFRESH TABLES", "REFRESH SCHEMA now"} {
+		if err := c.exec(stmt); err == nil {
+			t.Errorf("%s: expected usage error", stmt)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package repl
+
+import (
+	"strings"
+
+	"github.com/gocql/gocqlsh/cql/lexer"
+)
+
+type statement struct {
+	cql string
+	// line is the line number in the input the statement starts on
+	line int
+}
+
+// splitStatements splits input into statements on each unquoted semicolon, the
+// semicolons are not included in the statements. Any trailing input which is
+// not terminated is returned as the last statement, comments preceding a
+// statement and empty statements are dropped.
+func splitStatements(input string) []statement {
+	var stmts []statement
+
+	l := lexer.Lex(input)
+	line := 1
+	start := -1
+	startLine := 0
+	for {
+		pos := l.Pos()
+		item := l.Item()
+		switch item.Typ {
+		case lexer.ItemEOF:
+			if start >= 0 {
+				stmts = append(stmts, statement{strings.TrimSpace(input[start:]), startLine})
+			}
+			return stmts
+		case lexer.ItemSemiColon:
+			if start >= 0 {
+				stmts = append(stmts, statement{strings.TrimSpace(input[start:pos]), startLine})
+			}
+			start = -1
+		case lexer.ItemWhitespace, lexer.ItemComment:
+		default:
+			if start < 0 {
+				start, startLine = pos, line
+			}
+		}
+
+		line += strings.Count(item.Val, "\n")
+	}
+}
+
+// statementComplete reports whether input ends with a terminated statement,
+// that is the last item which is not whitespace or a comment is an unquoted
+// semicolon.
+func statementComplete(input string) bool {
+	l := lexer.Lex(input)
+	var last lexer.Item
+	for {
+		item := l.Item()
+		switch item.Typ {
+		case lexer.ItemEOF:
+			return last.Typ == lexer.ItemSemiColon
+		case lexer.ItemWhitespace:
+		case lexer.ItemComment:
+			if strings.HasPrefix(item.Val, "/*") && (len(item.Val) < 4 || !strings.HasSuffix(item.Val, "*/")) {
+				// unterminated block comment
+				return false
+			}
+		default:
+			last = item
+		}
+	}
+}
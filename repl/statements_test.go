@@ -0,0 +1,63 @@
+package repl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := [...]struct {
+		in  string
+		exp []statement
+	}{
+		{"", nil},
+		{"select * from t", []statement{{"select * from t", 1}}},
+		{"select * from t;", []statement{{"select * from t", 1}}},
+		{"use ks; select * from t;", []statement{{"use ks", 1}, {"select * from t", 1}}},
+		{"use ks;;  ;select * from t", []statement{{"use ks", 1}, {"select * from t", 1}}},
+		{"insert into t (a) values ('a;b');", []statement{{"insert into t (a) values ('a;b')", 1}}},
+		{`select "a;b" from t;`, []statement{{`select "a;b" from t`, 1}}},
+		{
+			"-- create things; not a statement\nuse ks;\n\n/* a\nmulti line; comment */\ncreate table t (\n  a int primary key -- the key;\n);\n",
+			[]statement{{"use ks", 2}, {"create table t (\n  a int primary key -- the key;\n)", 6}},
+		},
+		{"select 'multi\nline' from t;\nuse ks;", []statement{{"select 'multi\nline' from t", 1}, {"use ks", 3}}},
+		{"use ks; -- trailing comment\n", []statement{{"use ks", 1}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			stmts := splitStatements(test.in)
+			if !reflect.DeepEqual(test.exp, stmts) {
+				t.Fatalf("expected %+v got %+v", test.exp, stmts)
+			}
+		})
+	}
+}
+
+func TestStatementComplete(t *testing.T) {
+	tests := [...]struct {
+		in       string
+		complete bool
+	}{
+		{"", false},
+		{"select * from t", false},
+		{"select * from t;", true},
+		{"select * from t;\n", true},
+		{"create table t (\n  a int primary key\n)", false},
+		{"create table t (\n  a int primary key\n);", true},
+		{"insert into t (a) values ('a;", false},
+		{"insert into t (a) values ('a;\nb');", true},
+		{"select * from t; -- done", true},
+		{"select * from t -- not done;", false},
+		{"select * from t; /* still in a comment", false},
+		{"select * from t; /**/", true},
+		{"use ks; select", false},
+	}
+
+	for _, test := range tests {
+		if complete := statementComplete(test.in); complete != test.complete {
+			t.Errorf("%q: expected complete=%v got %v", test.in, test.complete, complete)
+		}
+	}
+}
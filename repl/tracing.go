@@ -0,0 +1,212 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/gocql/gocqlsh/cql/lexer"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// maxTraceWait is how long to wait for a trace session to be completed by the
+// coordinator before giving up.
+const maxTraceWait = 10 * time.Second
+
+// traceIDs is a gocql.Tracer which records the trace session ids of each
+// request instead of writing them out.
+type traceIDs struct {
+	ids []gocql.UUID
+}
+
+func (t *traceIDs) Trace(traceID []byte) {
+	id, err := gocql.UUIDFromBytes(traceID)
+	if err != nil {
+		return
+	}
+	t.ids = append(t.ids, id)
+}
+
+type traceSession struct {
+	id          gocql.UUID
+	request     string
+	coordinator net.IP
+	client      net.IP
+	started     time.Time
+	// duration is in microseconds
+	duration int
+	events   []traceEvent
+}
+
+type traceEvent struct {
+	activity  string
+	timestamp time.Time
+	source    net.IP
+	// elapsed is in microseconds since the session started on source
+	elapsed int
+	thread  string
+}
+
+func (c *CQL) tracingCommand(_ string, args *lexer.Lexer) error {
+	arg := args.ItemNoWS()
+	switch {
+	case arg.Typ == lexer.ItemEOF || arg.Typ == lexer.ItemSemiColon:
+		state := "disabled"
+		if c.tracing {
+			state = "enabled"
+		}
+		_, err := fmt.Fprintf(c.out, "Tracing is currently %s.\n", state)
+		return err
+	case arg.Typ == lexer.ItemKeyword && arg.Val == "on":
+		if c.tracing {
+			_, err := fmt.Fprintln(c.out, "Tracing is already enabled.")
+			return err
+		}
+		c.tracing = true
+		_, err := fmt.Fprintln(c.out, "Now tracing requests.")
+		return err
+	case strings.EqualFold(arg.Val, "off"):
+		if !c.tracing {
+			_, err := fmt.Fprintln(c.out, "Tracing is not enabled.")
+			return err
+		}
+		c.tracing = false
+		_, err := fmt.Fprintln(c.out, "Disabled tracing.")
+		return err
+	}
+
+	return fmt.Errorf("usage: TRACING [ON | OFF]")
+}
+
+// showSession handles SHOW SESSION <uuid>.
+func (c *CQL) showSession(args *lexer.Lexer) error {
+	arg := args.ItemNoWS()
+	if arg.Typ != lexer.ItemUUID {
+		return fmt.Errorf("usage: SHOW SESSION <uuid>")
+	}
+
+	id, err := gocql.ParseUUID(arg.Val)
+	if err != nil {
+		return err
+	}
+
+	return c.showTrace(id)
+}
+
+func (c *CQL) showTrace(id gocql.UUID) error {
+	session, err := c.fetchTrace(id)
+	if err != nil {
+		return err
+	}
+
+	return c.renderTrace(c.out, session)
+}
+
+// fetchTrace reads the trace session id, waiting for the coordinator to
+// complete it.
+func (c *CQL) fetchTrace(id gocql.UUID) (*traceSession, error) {
+	session := &traceSession{id: id}
+
+	deadline := time.Now().Add(maxTraceWait)
+	wait := 10 * time.Millisecond
+	for {
+		var duration *int
+		err := c.db.Query(`SELECT request, coordinator, client, started_at, duration
+			FROM system_traces.sessions WHERE session_id = ?`, id).Consistency(gocql.One).Scan(
+			&session.request, &session.coordinator, &session.client, &session.started, &duration)
+		if err != nil && err != gocql.ErrNotFound {
+			return nil, err
+		}
+
+		// the duration is written once the request has completed
+		if err == nil && duration != nil {
+			session.duration = *duration
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("trace session %v was not complete after %v", id, maxTraceWait)
+		}
+
+		time.Sleep(wait)
+		if wait < time.Second {
+			wait *= 2
+		}
+	}
+
+	iter := c.db.Query(`SELECT event_id, activity, source, source_elapsed, thread
+		FROM system_traces.events WHERE session_id = ?`, id).Consistency(gocql.One).Iter()
+
+	var (
+		eventID gocql.UUID
+		event   traceEvent
+	)
+	for iter.Scan(&eventID, &event.activity, &event.source, &event.elapsed, &event.thread) {
+		event.timestamp = eventID.Time()
+		session.events = append(session.events, event)
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (c *CQL) renderTrace(w io.Writer, session *traceSession) error {
+	if _, err := fmt.Fprintf(w, "\nTracing session: %v\n\n", session.id); err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoWrapText(false)
+
+	var header []string
+	for _, col := range [...]string{"activity", "timestamp", "source", "source_elapsed", "thread"} {
+		header = append(header, c.au.Magenta(col).String())
+	}
+	table.SetHeader(header)
+
+	row := func(activity string, timestamp time.Time, source net.IP, elapsed int, thread string) {
+		table.Append([]string{
+			activity,
+			c.format.time(timestamp),
+			source.String(),
+			strconv.Itoa(elapsed),
+			thread,
+		})
+	}
+
+	row("Execute CQL3 query", session.started, session.coordinator, 0, "")
+	for _, event := range session.events {
+		row(event.activity, event.timestamp, event.source, event.elapsed, event.thread)
+	}
+	row("Request complete", session.started.Add(time.Duration(session.duration)*time.Microsecond),
+		session.coordinator, session.duration, "")
+
+	table.Render()
+	return nil
+}
+
+// showTraces renders each trace recorded by tracer, errors fetching a trace
+// do not stop the remaining traces being shown.
+func (c *CQL) showTraces(tracer *traceIDs) error {
+	var errs []string
+	for _, id := range tracer.ids {
+		if err := c.showTrace(id); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package repl
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+func TestTracingCommand(t *testing.T) {
+	c, out := newTestCQL()
+
+	tests := [...]struct {
+		stmt    string
+		output  string
+		tracing bool
+	}{
+		{"TRACING", "Tracing is currently disabled.\n", false},
+		{"TRACING OFF", "Tracing is not enabled.\n", false},
+		{"tracing on", "Now tracing requests.\n", true},
+		{"TRACING ON;", "Tracing is already enabled.\n", true},
+		{"TRACING", "Tracing is currently enabled.\n", true},
+		{"TRACING OFF", "Disabled tracing.\n", false},
+	}
+
+	for _, test := range tests {
+		out.Reset()
+		if err := c.exec(test.stmt); err != nil {
+			t.Fatalf("%s: %v", test.stmt, err)
+		}
+
+		if out.String() != test.output {
+			t.Errorf("%s: expected output %q got %q", test.stmt, test.output, out.String())
+		} else if c.tracing != test.tracing {
+			t.Errorf("%s: expected tracing=%v got %v", test.stmt, test.tracing, c.tracing)
+		}
+	}
+}
+
+func TestRenderTrace(t *testing.T) {
+	c, _ := newTestCQL()
+	c.format.timeZone = time.UTC
+	c.format.timeFormat = "15:04:05.000000"
+
+	started := time.Date(2017, 1, 1, 12, 0, 0, 0, time.UTC)
+	coordinator := net.IPv4(10, 0, 0, 1)
+	session := &traceSession{
+		id:          gocql.TimeUUID(),
+		coordinator: coordinator,
+		started:     started,
+		duration:    1500,
+		events: []traceEvent{
+			{"Parsing select", started.Add(100 * time.Microsecond), coordinator, 100, "Native-Transport-Requests-1"},
+			{"Read 1 live rows", started.Add(900 * time.Microsecond), net.IPv4(10, 0, 0, 2), 350, "ReadStage-2"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := c.renderTrace(&buf, session); err != nil {
+		t.Fatal(err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "Tracing session: "+session.id.String()) {
+		t.Errorf("trace output missing session id:\n%s", output)
+	}
+
+	rows := [...][]string{
+		{"Execute CQL3 query", "12:00:00.000000", "10.0.0.1", "0"},
+		{"Parsing select", "12:00:00.000100", "10.0.0.1", "100", "Native-Transport-Requests-1"},
+		{"Read 1 live rows", "12:00:00.000900", "10.0.0.2", "350", "ReadStage-2"},
+		{"Request complete", "12:00:00.001500", "10.0.0.1", "1500"},
+	}
+
+	lines := strings.Split(output, "\n")
+	for _, row := range rows {
+		found := false
+		for _, line := range lines {
+			if !strings.Contains(line, row[0]) {
+				continue
+			}
+
+			found = true
+			for _, cell := range row[1:] {
+				if !strings.Contains(line, " "+cell+" ") {
+					t.Errorf("row %q missing %q: %s", row[0], cell, line)
+				}
+			}
+		}
+
+		if !found {
+			t.Errorf("trace output missing row %q:\n%s", row[0], output)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/gocql/gocql"
+)
+
+type sslConfig struct {
+	caFile   string
+	certFile string
+	keyFile  string
+	// noVerify disables hostname validation, if a CA is given the server
+	// certificate chain is still verified against it.
+	noVerify bool
+}
+
+func (s *sslConfig) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{}
+
+	if s.caFile != "" {
+		pem, err := ioutil.ReadFile(s.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file: %v", err)
+		}
+
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %q", s.caFile)
+		}
+	}
+
+	if s.certFile != "" || s.keyFile != "" {
+		if s.certFile == "" || s.keyFile == "" {
+			return nil, errors.New("both a client certificate and key are required")
+		}
+
+		cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if s.noVerify {
+		cfg.InsecureSkipVerify = true
+		if cfg.RootCAs != nil {
+			roots := cfg.RootCAs
+			cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+				return verifyChain(roots, cs.PeerCertificates)
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// verifyChain verifies the peer certificates against roots without checking
+// the hostname they were issued for.
+func verifyChain(roots *x509.CertPool, certs []*x509.Certificate) error {
+	if len(certs) == 0 {
+		return errors.New("server did not present a certificate")
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(opts)
+	return err
+}
+
+func (s *sslConfig) sslOptions() (*gocql.SslOptions, error) {
+	cfg, err := s.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return &gocql.SslOptions{
+		Config:                 cfg,
+		EnableHostVerification: !s.noVerify,
+	}, nil
+}
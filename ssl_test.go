@@ -0,0 +1,212 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, tmpl *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+
+	parentCert, parentKey := tmpl, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) writePEM(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return certFile, keyFile
+}
+
+func newTestCA(t *testing.T) *testCert {
+	return newTestCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gocqlsh test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}, nil)
+}
+
+// serveTLS starts a TLS listener with a certificate for localhost signed by ca
+// which completes handshakes and closes connections.
+func serveTLS(t *testing.T, ca *testCert) string {
+	t.Helper()
+
+	server := newTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{server.der},
+			PrivateKey:  server.key,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func dialTLS(addr, serverName string, cfg *tls.Config) error {
+	cfg = cfg.Clone()
+	cfg.ServerName = serverName
+
+	conn, err := tls.Dial("tcp", addr, cfg)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func TestSSLConfig_Verify(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile, _ := ca.writePEM(t, dir, "ca")
+	addr := serveTLS(t, ca)
+
+	otherCA := newTestCA(t)
+	otherCAFile, _ := otherCA.writePEM(t, dir, "other")
+
+	tests := [...]struct {
+		name       string
+		ssl        sslConfig
+		serverName string
+		ok         bool
+	}{
+		{"trusted", sslConfig{caFile: caFile}, "localhost", true},
+		{"wrong host", sslConfig{caFile: caFile}, "cassandra.example.com", false},
+		{"wrong host no verify", sslConfig{caFile: caFile, noVerify: true}, "cassandra.example.com", true},
+		{"untrusted", sslConfig{caFile: otherCAFile}, "localhost", false},
+		{"untrusted no verify", sslConfig{caFile: otherCAFile, noVerify: true}, "localhost", false},
+		{"no ca no verify", sslConfig{noVerify: true}, "localhost", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := test.ssl.tlsConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = dialTLS(addr, test.serverName, cfg)
+			if test.ok && err != nil {
+				t.Fatalf("expected handshake to succeed: %v", err)
+			} else if !test.ok && err == nil {
+				t.Fatal("expected handshake to fail")
+			}
+		})
+	}
+}
+
+func TestSSLConfig_ClientCert(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	client := newTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "client"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+	certFile, keyFile := client.writePEM(t, dir, "client")
+
+	ssl := sslConfig{certFile: certFile, keyFile: keyFile}
+	cfg, err := ssl.tlsConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Certificates) != 1 {
+		t.Fatalf("expected 1 client certificate got %d", len(cfg.Certificates))
+	}
+
+	ssl = sslConfig{certFile: certFile}
+	if _, err := ssl.tlsConfig(); err == nil {
+		t.Fatal("expected error when client key is missing")
+	}
+}
+
+func TestSSLConfig_BadCA(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := ioutil.WriteFile(caFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ssl := sslConfig{caFile: caFile}
+	if _, err := ssl.tlsConfig(); err == nil {
+		t.Fatal("expected error for CA file without certificates")
+	}
+}